@@ -0,0 +1,1389 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/clmnin/wordcount/go/wordcount"
+	"golang.org/x/text/language"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run does the work of main with the command line arguments args and returns
+// the exit code, so deferred cleanup such as stopping the profiler happens
+// before the process exits.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("simple-go", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	top := flags.Int("top", 0, "print only the `N` most frequent words (0 or less prints all)")
+	tail := flags.Int("tail", 0, "print only the `N` least frequent words, in the order of the list (0 or less prints all)")
+	sortBy := flags.String("sort", "count", "order the output by `key`: count (most frequent first) or word (alphabetical)")
+	tie := flags.String("tie", "alpha", "order words with the same count by `policy`: alpha (alphabetical) or first-seen (the order they first appear in)")
+	asc := flags.Bool("asc", false, "sort from the least to the most frequent word")
+	minCount := flags.Int("min", 1, "drop words that occur fewer than `N` times")
+	chars := flags.Bool("chars", false, "count characters instead of words, leaving out whitespace")
+	delimiter := flags.String("delimiter", "", "count the fields between `char` and line ends instead of whitespace separated words; \\t stands for tab")
+	trimFields := flags.Bool("trim-fields", false, "trim the whitespace around each -delimiter field")
+	tokenRegex := flags.String("token-regex", "", "count the matches of regular expression `pattern` as the words, e.g. \\p{L}+ for runs of letters; words are split on whitespace when empty")
+	filesFrom := flags.String("files-from", "", "also count the inputs listed one per line in `manifest` (- reads the list from standard input); blank lines and lines starting with # are ignored")
+	ext := flags.String("ext", "", "count only files ending in `suffix`, such as .txt, in directory arguments")
+	timeout := flags.Duration("timeout", 30*time.Second, "give up on fetching an http or https URL argument after `duration`")
+	encoding := flags.String("encoding", "auto", "read input as `name`: auto, utf-8, utf-16le or utf-16be; auto goes by the byte order mark and falls back to utf-8")
+	skipInvalid := flags.Bool("skip-invalid", false, "skip words that are not valid UTF-8, such as bytes from binary or Latin-1 files")
+	stripPunct := flags.Bool("strip-punct", false, "trim leading and trailing punctuation from words, so \"end.\" counts as \"end\"")
+	lowercase := flags.Bool("lowercase", true, "lowercase words before counting them; -lowercase=false counts them as they are written")
+	caseSensitive := flags.Bool("case-sensitive", false, "same as -lowercase=false, kept for older scripts")
+	fold := flags.Bool("ignore-case-fold", false, "group words with Unicode case folding instead of lowercasing, so STRASSE and straße are one word")
+	lang := flags.String("lang", "", "lowercase words with the rules of language `tag`, such as tr or el; the default rules are used when empty")
+	noNumbers := flags.Bool("no-numbers", false, "skip words made only of digits, such as 42 (3rd is still counted)")
+	include := flags.String("include", "", "count only words matching regular expression `pattern`, checked after lowercasing")
+	minLen := flags.Int("minlen", 0, "skip words shorter than `N` characters")
+	maxLen := flags.Int("maxlen", 0, "skip words longer than `N` characters (0 means no limit)")
+	splitHyphens := flags.Bool("split-hyphens", false, "count the parts of hyphenated words, like state-of-the-art, as separate words")
+	stem := flags.Bool("stem", false, "count the Porter stem of each word, so run, runs and running are one word")
+	stopwords := flags.String("stopwords", "", "skip the words listed one per line in `file`; matching is done after lowercasing, so list them in lower case")
+	ngram := flags.Int("ngram", 1, "count phrases of `N` consecutive words instead of single words")
+	cooccur := flags.Int("cooccur", 0, "count pairs of different words that appear within a window of `W` words, printed as \"wordA wordB count\"")
+	bufferSize := flags.Int("buffer", bufio.MaxScanTokenSize, "allow words, or lines with -token-regex, up to `bytes` long")
+	maxDistinct := flags.Int("max-distinct", 0, "stop adding new words once `N` distinct words are counted, as a guard for untrusted input; words already seen keep counting (0 means no limit)")
+	sample := flags.Float64("sample", 1, "count each word only with probability `P` (0 to 1) and scale the counts up, for a quick estimate")
+	seed := flags.Int64("seed", 1, "seed for the random choices of -sample, so runs can be repeated")
+	approxTop := flags.Int("approx-top", 0, "estimate only the `K` most frequent words with a count-min sketch of about 1MiB per input counted at once instead of keeping every word in memory; counts may be too high")
+	workers := flags.Int("workers", runtime.NumCPU(), "count the input in chunks, or several files at once, on `N` goroutines (1 counts serially)")
+	unique := flags.Bool("unique", false, "print only the number of distinct words left after filtering")
+	topPerLength := flags.Bool("top-per-length", false, "print the most frequent word of each length in characters, as \"length: word (count)\" lines")
+	byCount := flags.Bool("by-count", false, "print one line per count listing every word that occurs that many times")
+	bars := flags.Bool("bars", false, "draw a bar of # characters for each word, the most frequent one filling -bar-width columns")
+	barWidth := flags.Int("bar-width", 50, "width in `columns` of the longest bar drawn by -bars")
+	firstPos := flags.Bool("first-pos", false, "print the position of each word's first occurrence, starting at 1, instead of its count, in order of position")
+	dupes := flags.Bool("dupes", false, "count only words immediately repeated, like \"the the\", printed as \"word: N consecutive repeats\"")
+	byLetter := flags.Bool("by-letter", false, "group the word list under a header for each first letter, with # for words not starting with a letter")
+	format := flags.String("format", "", "print each entry with text/template `template`, using {{.Word}} and {{.Count}}; \\t and \\n outside {{ }} stand for tab and newline, and each entry ends with a newline")
+	noAlign := flags.Bool("no-align", false, "never line up the columns of the plain text output; they are only lined up on a terminal")
+	pct := flags.Bool("pct", false, "print each word's share of the total as a percentage in the plain text output")
+	summary := flags.Bool("summary", false, "print a total and unique word count after the word list")
+	meta := flags.String("meta", "", "also write the total, unique count and top word as a JSON object to `file`")
+	progress := flags.Bool("progress", false, "report the number of words counted so far to standard error while counting")
+	merge := flags.String("merge", "", "add the counts saved in `file` by an earlier run, as plain text, -tsv or -json output, to the new counts")
+	diff := flags.String("diff", "", "compare the input with `file`, printing each word with both counts and the difference")
+	allowEmpty := flags.Bool("allow-empty", false, "exit with status 0 instead of 3 when the input has no words")
+	output := flags.String("o", "", "write the word list to `file` instead of standard output")
+	cpuprofile := flags.String("cpuprofile", "", "write a CPU profile to `file`")
+	memprofile := flags.String("memprofile", "", "write a heap profile to `file` once counting is done")
+	asJSON := flags.Bool("json", false, "print the counts as a JSON array of {\"word\", \"count\"} objects")
+	asCSV := flags.Bool("csv", false, "print the counts as CSV with a word,count header row")
+	asTSV := flags.Bool("tsv", false, "print the counts as tab separated word and count columns")
+	quiet := flags.Bool("quiet", false, "do not print warnings and notes, such as skipped files; errors are still printed and the exit status is unchanged")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] [file, directory or url ...]\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Counts the words in the named files, the files under the named directories and http or https URLs, or in standard input when none are given, either as arguments or with -files-from.")
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "The exit status is 1 on errors, 2 for bad flags, 3 when no words were found and 130 when interrupted.")
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	report := reporter{w: stderr, quiet: *quiet}
+	switch *encoding {
+	case "auto", "utf-8", "utf-16le", "utf-16be":
+	default:
+		report.printf("unknown -encoding %q", *encoding)
+		return 2
+	}
+	var lineTmpl *template.Template
+	if *format != "" {
+		var err error
+		lineTmpl, err = template.New("format").Parse(unescapeFormat(*format) + "\n")
+		if err != nil {
+			report.printf("invalid -format: %v", err)
+			return 2
+		}
+	}
+	if *tie != "alpha" && *tie != "first-seen" {
+		report.printf("unknown -tie %q, want alpha or first-seen", *tie)
+		return 2
+	}
+	if *sortBy != "count" && *sortBy != "word" {
+		report.printf("unknown -sort %q, want count or word", *sortBy)
+		return 2
+	}
+	if *barWidth < 1 {
+		report.printf("invalid -bar-width %d, want at least 1", *barWidth)
+		return 2
+	}
+	if *top > 0 && *tail > 0 {
+		report.printf("-top and -tail cannot be used together")
+		return 2
+	}
+	// the sketch keeps no set of the words seen to take their order from
+	if *tie == "first-seen" && *approxTop > 0 {
+		report.printf("-tie first-seen cannot be used with -approx-top")
+		return 2
+	}
+	if *cooccur > 0 && *ngram > 1 {
+		report.printf("-cooccur and -ngram cannot be used together")
+		return 2
+	}
+	if !(*sample > 0 && *sample <= 1) {
+		report.printf("invalid -sample %v, want a probability above 0 and at most 1", *sample)
+		return 2
+	}
+	if *firstPos {
+		// these would take the positions for counts
+		var conflicts []string
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "min", "asc", "pct", "summary", "meta", "bars", "by-count", "top-per-length", "json", "csv", "diff", "cooccur", "dupes", "sample", "approx-top", "merge":
+				conflicts = append(conflicts, "-"+f.Name)
+			}
+		})
+		if len(conflicts) > 0 {
+			report.printf("-first-pos cannot be used with %s", strings.Join(conflicts, ", "))
+			return 2
+		}
+	}
+	// the sampled counts are scaled up on their own, out of the limited map
+	if *sample < 1 && *maxDistinct > 0 {
+		report.printf("-sample cannot be used with -max-distinct")
+		return 2
+	}
+	if *dupes && (*cooccur > 0 || *ngram > 1) {
+		report.printf("-dupes cannot be used with -cooccur or -ngram")
+		return 2
+	}
+	formats := 0
+	for _, set := range []bool{*asJSON, *asCSV, *asTSV} {
+		if set {
+			formats++
+		}
+	}
+	if formats > 1 {
+		report.printf("only one of -json, -csv and -tsv can be used")
+		return 2
+	}
+
+	// Start Profiler, only when asked for
+	if *cpuprofile != "" {
+		stop, err := startCPUProfile(*cpuprofile)
+		if err != nil {
+			report.printf("could not start CPU profile: %v", err)
+			return 1
+		}
+		// End Profiler
+		defer stop()
+	}
+	opts := wordcount.Options{
+		Chars:        *chars,
+		SkipInvalid:  *skipInvalid,
+		StripPunct:   *stripPunct,
+		SplitHyphens: *splitHyphens,
+		NoNumbers:    *noNumbers,
+		Lowercase:    *lowercase && !*caseSensitive,
+		Fold:         *fold,
+		MinLen:       *minLen,
+		MaxLen:       *maxLen,
+		Stem:         *stem,
+		NGram:        *ngram,
+		Cooccur:      *cooccur,
+		Dupes:        *dupes,
+		FirstPos:     *firstPos,
+		MaxTokenSize: *bufferSize,
+		MaxDistinct:  *maxDistinct,
+		ApproxTop:    *approxTop,
+		Sample:       *sample,
+		Seed:         *seed,
+		Workers:      *workers,
+	}
+	if *lang != "" {
+		tag, err := language.Parse(*lang)
+		if err != nil {
+			report.printf("invalid -lang %q: %v", *lang, err)
+			return 2
+		}
+		opts.Lang = tag
+	}
+	if *delimiter != "" {
+		d := formatEscapes.Replace(*delimiter)
+		r, size := utf8.DecodeRuneInString(d)
+		if size != len(d) || r == utf8.RuneError {
+			report.printf("-delimiter must be a single character, got %q", *delimiter)
+			return 2
+		}
+		opts.Delimiter = r
+		opts.TrimFields = *trimFields
+	}
+	if *tokenRegex != "" {
+		re, err := regexp.Compile(*tokenRegex)
+		if err != nil {
+			report.printf("invalid -token-regex: %v", err)
+			return 2
+		}
+		opts.TokenRegex = re
+	}
+	if *include != "" {
+		re, err := regexp.Compile(*include)
+		if err != nil {
+			report.printf("invalid -include: %v", err)
+			return 2
+		}
+		opts.Include = re
+	}
+	if *stopwords != "" {
+		stop, err := loadStopWords(*stopwords)
+		if err != nil {
+			report.printf("could not load stop words: %v", err)
+			return 1
+		}
+		opts.StopWords = stop
+	}
+	// limitHit is set from the goroutines counting files in parallel
+	var limitHit atomic.Bool
+	opts.LimitReached = func() { limitHit.Store(true) }
+	if opts.Sample < 1 {
+		report.warnf("note: -sample counts are estimates scaled up from about %g of the words", opts.Sample)
+	}
+	if opts.ApproxTop > 0 {
+		// printed even with -quiet, so estimated counts are never taken for exact ones
+		report.printf("note: -approx-top counts are estimates from a count-min sketch and may be too high")
+	}
+	// scanned is the running total behind -progress, shared by every worker and file
+	var scanned atomic.Int64
+	if *progress {
+		opts.Progress = func(n int) {
+			now := scanned.Add(int64(n))
+			// only report when the total passes another multiple of progressEvery
+			if now/wordcount.ProgressEvery != (now-int64(n))/wordcount.ProgressEvery {
+				report.printf("%d words counted", now)
+			}
+		}
+	}
+	// invalid is the number of words that are not valid UTF-8, warned about unless skipped
+	var invalid atomic.Int64
+	opts.Invalid = func() { invalid.Add(1) }
+	// init an object of type Map<string, int>
+	counts := make(map[string]int)
+	// the saved counts are read before -o is created, which may be the same file
+	if *merge != "" {
+		saved, err := loadCounts(*merge)
+		if err != nil {
+			report.printf("could not load -merge file: %v", err)
+			return 1
+		}
+		counts = saved
+	}
+	// out is where the word list goes, opened up front so a bad path fails before counting
+	var out io.Writer = stdout
+	var outFile *os.File
+	if *output != "" {
+		// the list is written to a temporary file in the same directory and
+		// only renamed over -o once written, so a failed run leaves -o, which
+		// may be the -merge file, as it was
+		f, err := os.CreateTemp(filepath.Dir(*output), "."+filepath.Base(*output)+".*")
+		if err != nil {
+			report.printf("could not create output file: %v", err)
+			return 1
+		}
+		defer func() {
+			// both fail harmlessly once the file is renamed
+			f.Close()
+			os.Remove(f.Name())
+		}()
+		// keep the mode of the file replaced, or that of a new file
+		mode := os.FileMode(0o644)
+		if info, err := os.Stat(*output); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := f.Chmod(mode); err != nil {
+			report.printf("could not create output file: %v", err)
+			return 1
+		}
+		out, outFile = f, f
+	}
+	// ctrl-C stops the counting, and the words counted so far are still printed
+	done := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	// finished lets the goroutine go once run returns
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-sigs:
+			// a second ctrl-C kills the process as usual
+			signal.Stop(sigs)
+			close(done)
+		case <-finished:
+			signal.Stop(sigs)
+		}
+	}()
+	opts.Done = done
+	interrupted := false
+
+	src := source{encoding: *encoding, client: &http.Client{Timeout: *timeout}}
+	// order numbers the words in the order they are first seen, for -tie first-seen
+	var order map[string]int
+	if *tie == "first-seen" {
+		order = make(map[string]int)
+		// the saved file does not keep that order, so its words go first alphabetically
+		saved := make([]string, 0, len(counts))
+		for word := range counts {
+			saved = append(saved, word)
+		}
+		sort.Strings(saved)
+		for _, word := range saved {
+			order[word] = len(order)
+		}
+		opts.Seen = func(word string) {
+			if _, ok := order[word]; !ok {
+				order[word] = len(order)
+			}
+		}
+	}
+	// the inputs are the arguments followed by those listed in -files-from
+	inputs := flags.Args()
+	if *filesFrom != "" {
+		listed, err := readManifest(*filesFrom, stdin)
+		if err != nil {
+			report.printf("could not read -files-from list: %v", err)
+			return 1
+		}
+		inputs = append(inputs, listed...)
+	}
+	failed := false
+	if len(inputs) == 0 && *filesFrom == "" {
+		// stdin has no name to go by, so look for the gzip magic bytes instead
+		in, err := sniffGzip(stdin)
+		if err != nil {
+			report.printf("%v", err)
+			return 1
+		}
+		stdinCounts := counts
+		if opts.Sample < 1 {
+			// only the new counts are sampled, not those loaded with -merge
+			stdinCounts = make(map[string]int)
+		}
+		err = wordcount.CountWordsInto(stdinCounts, decodeInput(in, *encoding), opts)
+		if errors.Is(err, wordcount.ErrStopped) {
+			interrupted = true
+		} else if err != nil {
+			report.printf("%v", bufferHint(err))
+			return 1
+		}
+		if opts.Sample < 1 {
+			wordcount.ScaleCounts(stdinCounts, opts.Sample)
+			wordcount.MergeCounts(counts, stdinCounts)
+		}
+	}
+	// every named file is counted and merged into the same map
+	files := inputFiles(inputs, *ext, func(err error) {
+		report.warnf("skipping: %v", err)
+		failed = true
+	})
+	// positions are only meaningful within one input
+	if *firstPos && len(files) > 1 {
+		report.printf("-first-pos needs a single input")
+		return 2
+	}
+	// counted reports whether the counts of a file are kept, given the error
+	// from counting it
+	counted := func(err error) bool {
+		if errors.Is(err, wordcount.ErrStopped) {
+			interrupted = true
+		} else if err != nil {
+			report.warnf("skipping file: %v", bufferHint(err))
+			failed = true
+			return false
+		}
+		return true
+	}
+	if opts.MaxDistinct > 0 {
+		// the files are counted into counts one after the other, so the limit
+		// keeps the first words read and the output is the same on every run;
+		// a file failing part way keeps the words read before the error
+		for _, path := range files {
+			counted(src.countFileInto(counts, path, opts))
+			if interrupted {
+				break
+			}
+		}
+	} else {
+		src.countFiles(files, opts, func(path string, fileCounts map[string]int, err error) bool {
+			if counted(err) {
+				// only the new counts are sampled, not those loaded with -merge
+				if opts.Sample < 1 {
+					wordcount.ScaleCounts(fileCounts, opts.Sample)
+				}
+				wordcount.MergeCounts(counts, fileCounts)
+			}
+			return !interrupted
+		})
+	}
+	// each input has a sketch of its own, so their merged tops are cut back to K
+	if opts.ApproxTop > 0 && len(counts) > opts.ApproxTop {
+		top := topCounts(counts, nil, opts.ApproxTop, 1, func(a, b wordcount.Count) bool {
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Word < b.Word
+		})
+		counts = make(map[string]int, len(top))
+		for _, count := range top {
+			counts[count.Word] = count.Count
+		}
+	}
+	if limitHit.Load() {
+		report.warnf("warning: reached -max-distinct %d, words seen after that were not counted", opts.MaxDistinct)
+	}
+	if interrupted {
+		report.warnf("interrupted, printing the words counted so far")
+	}
+	// the other side of -diff is counted on its own with the same options
+	var other map[string]int
+	if *diff != "" && !interrupted {
+		var err error
+		diffOpts := opts
+		diffOpts.Seen = nil
+		other, err = src.countFile(*diff, diffOpts)
+		if err != nil {
+			report.printf("could not count -diff file: %v", bufferHint(err))
+			return 1
+		}
+	}
+	if opts.Sample < 1 {
+		wordcount.ScaleCounts(other, opts.Sample)
+	}
+
+	if *progress {
+		report.printf("%d words counted in total", scanned.Load())
+	}
+	if n := invalid.Load(); n > 0 && !opts.SkipInvalid {
+		report.warnf("warning: counted %d words that are not valid UTF-8, use -skip-invalid to skip them", n)
+	}
+
+	// Heap profile of the finished counts map
+	if *memprofile != "" {
+		if err := writeHeapProfile(*memprofile); err != nil {
+			report.printf("could not write memory profile: %v", err)
+			return 1
+		}
+	}
+
+	// before reports whether a is printed before b: by Count.Count, breaking ties with Count.Word,
+	// or by Count.Word alone for -sort word
+	before := func(a, b wordcount.Count) bool {
+		if *sortBy == "count" && a.Count != b.Count {
+			// positions read from the start of the input
+			if *asc || *firstPos {
+				return a.Count < b.Count
+			}
+			return a.Count > b.Count
+		}
+		if *tie == "first-seen" && *sortBy == "count" {
+			return a.Order < b.Order
+		}
+		return a.Word < b.Word
+	}
+	// ordered is a <List> of type <Count> (a struct); never nil so empty input encodes as []
+	var ordered []wordcount.Count
+	if *top > 0 && *top < len(counts) {
+		// only N entries are printed, so keep them in a heap instead of sorting every word
+		ordered = topCounts(counts, order, *top, *minCount, before)
+	} else {
+		ordered = make([]wordcount.Count, 0, len(counts))
+		// for word, count in range counts
+		for word, count := range counts {
+			// skip rare words below the -min threshold
+			if count < *minCount {
+				continue
+			}
+			// append to ordered
+			ordered = append(ordered, wordcount.Count{Word: word, Count: count, Order: order[word]})
+		}
+		if *tail > 0 && *tail < len(ordered) && !*firstPos {
+			// the rarest words are at the end from the most frequent down,
+			// whatever order -asc or -sort word print them in
+			sort.Slice(ordered, func(i, j int) bool {
+				a, b := ordered[i], ordered[j]
+				if a.Count != b.Count {
+					return a.Count > b.Count
+				}
+				if *tie == "first-seen" {
+					return a.Order < b.Order
+				}
+				return a.Word < b.Word
+			})
+			ordered = ordered[len(ordered)-*tail:]
+		}
+		sort.Slice(ordered, func(i, j int) bool {
+			return before(ordered[i], ordered[j])
+		})
+		// positions have no rarest words, so -first-pos keeps the last of the list
+		if *tail > 0 && *tail < len(ordered) {
+			ordered = ordered[len(ordered)-*tail:]
+		}
+	}
+
+	// total is the number of words counted, before any filtering
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+
+	// every line goes through one buffer rather than a write call each, which
+	// matters once there are millions of words; it is flushed below
+	terminal := isTerminal(out)
+	buf := bufio.NewWriter(out)
+	var werr error
+	switch {
+	case *unique:
+		distinct := 0
+		for _, count := range counts {
+			if count >= *minCount {
+				distinct++
+			}
+		}
+		_, werr = fmt.Fprintln(buf, distinct)
+	case other != nil:
+		werr = writeDiff(buf, diffCounts(counts, other), *top)
+	case *byCount:
+		werr = writeByCount(buf, counts, *minCount)
+	case *topPerLength:
+		werr = writeTopPerLength(buf, counts, *minCount)
+	case *byLetter:
+		werr = writeByLetter(buf, ordered)
+	case lineTmpl != nil:
+		werr = writeTemplate(buf, ordered, lineTmpl)
+	case *asJSON:
+		werr = json.NewEncoder(buf).Encode(ordered)
+	case *asCSV:
+		werr = writeCSV(buf, ordered)
+	case *asTSV:
+		werr = writeTSV(buf, ordered)
+	case *dupes:
+		werr = writeDupes(buf, ordered)
+	case *bars:
+		werr = writeBars(buf, ordered, *barWidth)
+	default:
+		// lining up the columns holds the whole list in memory, and only helps a reader
+		werr = writeText(buf, ordered, *pct, total, !*noAlign && terminal)
+	}
+	if werr == nil && *summary {
+		_, werr = fmt.Fprintf(buf, "total: %d words, %d unique\n", total, len(counts))
+	}
+	if werr == nil {
+		werr = buf.Flush()
+	}
+	if werr == nil && outFile != nil {
+		werr = outFile.Close()
+		if werr == nil {
+			werr = os.Rename(outFile.Name(), *output)
+		}
+	}
+	if werr != nil {
+		report.printf("could not write output: %v", werr)
+		return 1
+	}
+	if *meta != "" {
+		if err := writeMeta(*meta, metaOf(counts)); err != nil {
+			report.printf("could not write -meta file: %v", err)
+			return 1
+		}
+	}
+	if interrupted {
+		return 130
+	}
+	if failed {
+		return 1
+	}
+	// scripts can tell an empty input from a successful count
+	if len(counts) == 0 && !*allowEmpty {
+		report.printf("no words found in the input")
+		return 3
+	}
+	return 0
+}
+
+// readManifest returns the inputs listed one per line in the file at path,
+// or in stdin when path is "-". Surrounding spaces are trimmed, and blank
+// lines and lines starting with # are skipped.
+func readManifest(path string, stdin io.Reader) ([]string, error) {
+	r := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var inputs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inputs = append(inputs, line)
+	}
+	return inputs, scanner.Err()
+}
+
+// inputFiles expands the directories in args into the regular files under
+// them, keeping only names ending in ext when it is set. Files and URLs in
+// args are kept as they are. A directory argument may be a symbolic link, but
+// links met in a directory are not followed, so links cannot make the walk
+// loop. Directories that cannot be read are passed to skip and left out.
+func inputFiles(args []string, ext string, skip func(error)) []string {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if isURL(arg) || err != nil || !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		// WalkDir does not follow a link at the root either
+		root, err := filepath.EvalSymlinks(arg)
+		if err != nil {
+			skip(err)
+			continue
+		}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				skip(err)
+				return nil
+			}
+			if d.Type().IsRegular() && strings.HasSuffix(path, ext) {
+				// the files are named under arg, as the user gave it
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				files = append(files, filepath.Join(arg, rel))
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// reporter writes the messages for the user, to stderr in run. With quiet
+// set the warnings are dropped, while errors are always written.
+type reporter struct {
+	w     io.Writer
+	quiet bool
+}
+
+// printf writes an error, or a message asked for such as -progress, as a
+// line.
+func (r reporter) printf(format string, args ...any) {
+	fmt.Fprintf(r.w, format+"\n", args...)
+}
+
+// warnf writes a warning as a line, unless the reporter is quiet. Warnings
+// are for problems that do not stop the count, such as a skipped file.
+func (r reporter) warnf(format string, args ...any) {
+	if !r.quiet {
+		r.printf(format, args...)
+	}
+}
+
+// source holds what is needed to open and decode the inputs named on the
+// command line.
+type source struct {
+	encoding string
+	client   *http.Client
+}
+
+// countFile opens the file or URL at path and counts its words. Inputs ending
+// in .gz are decompressed on the fly and the text is decoded as by
+// decodeInput.
+func (src source) countFile(path string, opts wordcount.Options) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := src.countFileInto(counts, path, opts)
+	return counts, err
+}
+
+// countFileInto is countFile adding the words to counts.
+func (src source) countFileInto(counts map[string]int, path string, opts wordcount.Options) error {
+	rc, err := src.open(path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	var in io.Reader = rc
+	name := path
+	if u, err := url.Parse(path); err == nil && isURL(path) {
+		name = u.Path
+	}
+	if filepath.Ext(name) == ".gz" {
+		zr, err := gzip.NewReader(rc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		defer zr.Close()
+		in = zr
+	}
+	if err := wordcount.CountWordsInto(counts, decodeInput(in, src.encoding), opts); err != nil {
+		// the partial counts are kept for ErrStopped
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// countFiles counts each of files with countFile and calls merge with the
+// results in the order of files, until merge returns false. With more than one
+// file and opts.Workers above 1, up to opts.Workers files are counted at the
+// same time, each one serially in its own goroutine, so the total goroutines
+// stay at opts.Workers. As the results are merged in order, the counts do not
+// depend on which file finishes first.
+func (src source) countFiles(files []string, opts wordcount.Options, merge func(path string, counts map[string]int, err error) bool) {
+	type result struct {
+		counts map[string]int
+		err    error
+	}
+	workers := opts.Workers
+	// Seen must hear of the words in the order of files
+	if len(files) > 1 && workers > 1 && opts.Seen == nil {
+		opts.Workers = 1
+	} else {
+		workers = 1
+	}
+	// each file gets its own buffered channel, so a finished goroutine never waits on merge
+	results := make([]chan result, len(files))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		// slots holds a token for each file being counted
+		slots := make(chan struct{}, workers)
+		for i, path := range files {
+			select {
+			case slots <- struct{}{}:
+			case <-quit:
+				return
+			}
+			go func() {
+				counts, err := src.countFile(path, opts)
+				results[i] <- result{counts, err}
+				<-slots
+			}()
+		}
+	}()
+	for i, path := range files {
+		r := <-results[i]
+		if !merge(path, r.counts, r.err) {
+			return
+		}
+	}
+}
+
+// open opens the file at path, or fetches it when path is an http or https URL.
+func (src source) open(path string) (io.ReadCloser, error) {
+	if !isURL(path) {
+		return os.Open(path)
+	}
+	resp, err := src.client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", path, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// isURL reports whether path names an http or https URL rather than a file.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// sniffGzip peeks at the first bytes of r and, when they are the gzip magic
+// number, returns a reader that decompresses r. Otherwise r is read as is.
+func sniffGzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr, nil
+	}
+	return br, nil
+}
+
+// decodeInput returns a reader of r as UTF-8 with any leading byte order mark
+// removed. With encoding "auto" the byte order mark decides between UTF-8 and
+// UTF-16, and input without one is read as UTF-8. "utf-16le" and "utf-16be"
+// force UTF-16 input and "utf-8" forces UTF-8 input.
+func decodeInput(r io.Reader, encoding string) io.Reader {
+	br := bufio.NewReader(r)
+	bom, _ := br.Peek(3)
+	hasUTF8BOM := bytes.HasPrefix(bom, []byte{0xEF, 0xBB, 0xBF})
+	hasLEBOM := bytes.HasPrefix(bom, []byte{0xFF, 0xFE})
+	hasBEBOM := bytes.HasPrefix(bom, []byte{0xFE, 0xFF})
+	switch {
+	case encoding == "utf-16le" || encoding == "auto" && hasLEBOM:
+		if hasLEBOM {
+			br.Discard(2)
+		}
+		return &utf16Reader{r: br, order: binary.LittleEndian}
+	case encoding == "utf-16be" || encoding == "auto" && hasBEBOM:
+		if hasBEBOM {
+			br.Discard(2)
+		}
+		return &utf16Reader{r: br, order: binary.BigEndian}
+	}
+	if hasUTF8BOM {
+		br.Discard(3)
+	}
+	return br
+}
+
+// utf16Reader transcodes UTF-16 text from r into UTF-8. Unpaired surrogates
+// are replaced by utf8.RuneError.
+type utf16Reader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	out   []byte // transcoded bytes not yet returned by Read
+	err   error
+}
+
+func (d *utf16Reader) Read(p []byte) (int, error) {
+	for len(d.out) < len(p) && d.err == nil {
+		c, err := d.unit()
+		if err != nil {
+			d.err = err
+			break
+		}
+		ch := rune(c)
+		if utf16.IsSurrogate(ch) {
+			ch = utf8.RuneError
+			// only take the next unit when it completes the pair
+			if next, err := d.r.Peek(2); err == nil {
+				if pair := utf16.DecodeRune(rune(c), rune(d.order.Uint16(next))); pair != utf8.RuneError {
+					d.r.Discard(2)
+					ch = pair
+				}
+			}
+		}
+		d.out = utf8.AppendRune(d.out, ch)
+	}
+	n := copy(p, d.out)
+	d.out = d.out[:copy(d.out, d.out[n:])]
+	if n == 0 {
+		return 0, d.err
+	}
+	return n, nil
+}
+
+// unit reads the next 16-bit code unit.
+func (d *utf16Reader) unit() (uint16, error) {
+	b, err := d.r.Peek(2)
+	if len(b) < 2 {
+		if len(b) == 0 && err == io.EOF {
+			return 0, io.EOF
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	d.r.Discard(2)
+	return d.order.Uint16(b), nil
+}
+
+// loadStopWords reads a newline separated list of words from the file at path.
+// Blank lines are ignored.
+func loadStopWords(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stop := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			stop[word] = true
+		}
+	}
+	return stop, scanner.Err()
+}
+
+// loadCounts reads the counts saved in the file at path, either as the JSON
+// list of -json or as lines of a word, a space or tab, and its count, which
+// covers the plain text and -tsv output.
+func loadCounts(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var saved []wordcount.Count
+		if err := json.Unmarshal(trimmed, &saved); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, count := range saved {
+			counts[count.Word] += count.Count
+		}
+		return counts, nil
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// the count is the last field, as n-grams hold spaces
+		cut := strings.LastIndexAny(line, " \t")
+		if cut < 0 {
+			return nil, fmt.Errorf("%s:%d: missing count", path, i+1)
+		}
+		count, err := strconv.Atoi(line[cut+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad count: %w", path, i+1, err)
+		}
+		// aligned output pads the word with spaces
+		counts[strings.TrimRight(line[:cut], " \t")] += count
+	}
+	return counts, nil
+}
+
+// writeHeapProfile runs a garbage collection, so the profile shows live
+// memory only, and writes the heap profile to the file at path.
+// A file that could not be written is removed rather than left half written.
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// startCPUProfile starts CPU profiling into the file at path and returns the
+// function that stops it and closes the file. When profiling cannot start the
+// file is closed and removed, so no empty profile is left behind.
+func startCPUProfile(path string) (stop func(), err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		f.Close()
+	}, nil
+}
+
+// percent returns count as a percentage of total, or 0 when total is 0.
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total) * 100
+}
+
+// topCounts returns the first k entries of counts, in the order given by
+// before, skipping words that occur fewer than minCount times. It keeps a heap
+// of at most k entries, taking O(n log k) time rather than the O(n log n) of
+// sorting all n words, and gives the same result as sorting and truncating.
+func topCounts(counts, order map[string]int, k, minCount int, before func(a, b wordcount.Count) bool) []wordcount.Count {
+	h := &countHeap{items: make([]wordcount.Count, 0, k), before: before}
+	for word, count := range counts {
+		if count < minCount {
+			continue
+		}
+		c := wordcount.Count{Word: word, Count: count, Order: order[word]}
+		if h.Len() < k {
+			heap.Push(h, c)
+		} else if before(c, h.items[0]) {
+			// c beats the last of the current top k, so it takes its place
+			h.items[0] = c
+			heap.Fix(h, 0)
+		}
+	}
+	sort.Slice(h.items, func(i, j int) bool {
+		return before(h.items[i], h.items[j])
+	})
+	return h.items
+}
+
+// countHeap is a heap.Interface that keeps the entry printed last at the root,
+// so the root is the one to drop when a better entry comes along.
+type countHeap struct {
+	items  []wordcount.Count
+	before func(a, b wordcount.Count) bool
+}
+
+func (h *countHeap) Len() int           { return len(h.items) }
+func (h *countHeap) Less(i, j int) bool { return h.before(h.items[j], h.items[i]) }
+func (h *countHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *countHeap) Push(x any)         { h.items = append(h.items, x.(wordcount.Count)) }
+func (h *countHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// writeText writes ordered to w one "word count" line per entry, followed by
+// the percentage of total when pct is set. With align the words are padded so
+// the counts line up in a column.
+func writeText(w io.Writer, ordered []wordcount.Count, pct bool, total int, align bool) error {
+	sep := " "
+	var tw *tabwriter.Writer
+	if align {
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		w, sep = tw, "\t"
+	}
+	for _, count := range ordered {
+		var err error
+		if pct {
+			_, err = fmt.Fprintf(w, "%s%s%d%s%.2f%%\n", count.Word, sep, count.Count, sep, percent(count.Count, total))
+		} else {
+			_, err = fmt.Fprintf(w, "%s%s%d\n", count.Word, sep, count.Count)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if tw != nil {
+		return tw.Flush()
+	}
+	return nil
+}
+
+// isTerminal reports whether w is a terminal rather than a file or a pipe.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatEscapes turns the escapes allowed in -format into the characters
+// they stand for, since shells pass them on literally.
+var formatEscapes = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n")
+
+// unescapeFormat applies formatEscapes to the text of a -format template
+// outside its {{ }} actions, so the strings in actions such as
+// {{printf "%s\n" .Word}} keep their escapes for the template to read.
+func unescapeFormat(format string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(format, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(format[start:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + len("}}")
+		b.WriteString(formatEscapes.Replace(format[:start]))
+		b.WriteString(format[start:end])
+		format = format[end:]
+	}
+	b.WriteString(formatEscapes.Replace(format))
+	return b.String()
+}
+
+// writeTemplate executes tmpl once for every entry of ordered.
+func writeTemplate(w io.Writer, ordered []wordcount.Count, tmpl *template.Template) error {
+	for _, count := range ordered {
+		if err := tmpl.Execute(w, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBars writes ordered as a bar chart, one "word |### count" line per
+// entry. The bars are scaled with integer math so that the highest count
+// takes width #s, and padded so the counts line up.
+func writeBars(w io.Writer, ordered []wordcount.Count, width int) error {
+	longest, highest := 0, 0
+	for _, count := range ordered {
+		longest = max(longest, utf8.RuneCountInString(count.Word))
+		highest = max(highest, count.Count)
+	}
+	for _, count := range ordered {
+		n := 0
+		if highest > 0 {
+			n = count.Count * width / highest
+		}
+		pad := longest - utf8.RuneCountInString(count.Word)
+		bar := strings.Repeat("#", n) + strings.Repeat(" ", width-n)
+		if _, err := fmt.Fprintf(w, "%s%s  |%s  %d\n", count.Word, strings.Repeat(" ", pad), bar, count.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDupes writes ordered as "word: N consecutive repeats" lines.
+func writeDupes(w io.Writer, ordered []wordcount.Count) error {
+	for _, count := range ordered {
+		if _, err := fmt.Fprintf(w, "%s: %d consecutive repeats\n", count.Word, count.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeByLetter writes ordered grouped by the lowercased first letter of each
+// word, one header line per letter in alphabetical order followed by the
+// group's indented "word count" lines. Words that do not start with a letter
+// go in a last group headed "#". Within a group ordered's order is kept.
+func writeByLetter(w io.Writer, ordered []wordcount.Count) error {
+	groups := make(map[string][]wordcount.Count)
+	for _, count := range ordered {
+		key := "#"
+		if first, _ := utf8.DecodeRuneInString(count.Word); unicode.IsLetter(first) {
+			key = string(unicode.ToLower(first))
+		}
+		groups[key] = append(groups[key], count)
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == "#" || keys[j] == "#" {
+			return keys[j] == "#" && keys[i] != "#"
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		if _, err := fmt.Fprintln(w, key); err != nil {
+			return err
+		}
+		for _, count := range groups[key] {
+			if _, err := fmt.Fprintf(w, "  %s %d\n", count.Word, count.Count); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeByCount inverts counts and writes a "count: word word ..." line for
+// every count reached by at least one word, highest count first and with the
+// words of a line sorted alphabetically. Counts below minCount are left out.
+func writeByCount(w io.Writer, counts map[string]int, minCount int) error {
+	buckets := make(map[int][]string)
+	for word, count := range counts {
+		if count < minCount {
+			continue
+		}
+		buckets[count] = append(buckets[count], word)
+	}
+	keys := make([]int, 0, len(buckets))
+	for count := range buckets {
+		keys = append(keys, count)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+	for _, count := range keys {
+		words := buckets[count]
+		sort.Strings(words)
+		if _, err := fmt.Fprintf(w, "%d: %s\n", count, strings.Join(words, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTopPerLength writes a "length: word (count)" line with the most
+// frequent word of each length in runes, shortest first. Of words with the
+// same count the first alphabetically is kept. Words below minCount are
+// skipped.
+func writeTopPerLength(w io.Writer, counts map[string]int, minCount int) error {
+	best := make(map[int]wordcount.Count)
+	for word, count := range counts {
+		if count < minCount {
+			continue
+		}
+		n := utf8.RuneCountInString(word)
+		if b, ok := best[n]; !ok || count > b.Count || count == b.Count && word < b.Word {
+			best[n] = wordcount.Count{Word: word, Count: count}
+		}
+	}
+	lengths := make([]int, 0, len(best))
+	for n := range best {
+		lengths = append(lengths, n)
+	}
+	sort.Ints(lengths)
+	for _, n := range lengths {
+		if _, err := fmt.Fprintf(w, "%d: %s (%d)\n", n, best[n].Word, best[n].Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV writes ordered to w as CSV, starting with a word,count header.
+func writeCSV(w io.Writer, ordered []wordcount.Count) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"word", "count"})
+	for _, count := range ordered {
+		cw.Write([]string{count.Word, strconv.Itoa(count.Count)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Diff holds the counts of a word in two inputs.
+type Diff struct {
+	Word string
+	A, B int
+}
+
+// Delta is how many more times the word occurs in the first input.
+func (d Diff) Delta() int {
+	return d.A - d.B
+}
+
+// diffCounts pairs up the words of a and b, with 0 for a word missing on one
+// side, ordered by the size of the difference, largest first, then by word.
+func diffCounts(a, b map[string]int) []Diff {
+	diffs := make([]Diff, 0, len(a))
+	for word, count := range a {
+		diffs = append(diffs, Diff{word, count, b[word]})
+	}
+	for word, count := range b {
+		if _, ok := a[word]; !ok {
+			diffs = append(diffs, Diff{word, 0, count})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		di, dj := abs(diffs[i].Delta()), abs(diffs[j].Delta())
+		if di != dj {
+			return di > dj
+		}
+		return diffs[i].Word < diffs[j].Word
+	})
+	return diffs
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// writeDiff writes one "word a b delta" line per entry of diffs, only the
+// first top entries when top is above 0.
+func writeDiff(w io.Writer, diffs []Diff, top int) error {
+	if top > 0 && top < len(diffs) {
+		diffs = diffs[:top]
+	}
+	for _, d := range diffs {
+		if _, err := fmt.Fprintf(w, "%s %d %d %+d\n", d.Word, d.A, d.B, d.Delta()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTSV writes ordered to w as "word<tab>count" lines. Tabs inside a word
+// are replaced by spaces so the columns stay aligned.
+func writeTSV(w io.Writer, ordered []wordcount.Count) error {
+	for _, count := range ordered {
+		word := strings.ReplaceAll(count.Word, "\t", " ")
+		if _, err := fmt.Fprintf(w, "%s\t%d\n", word, count.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Meta is the summary of a count written by -meta. TopWord is empty and
+// TopCount 0 when no words were counted.
+type Meta struct {
+	Total    int    `json:"total"`
+	Unique   int    `json:"unique"`
+	TopWord  string `json:"top_word"`
+	TopCount int    `json:"top_count"`
+}
+
+// metaOf sums up counts. Of the most frequent words, the first alphabetically
+// is the top word.
+func metaOf(counts map[string]int) Meta {
+	m := Meta{Unique: len(counts)}
+	for word, count := range counts {
+		m.Total += count
+		if count > m.TopCount || count == m.TopCount && word < m.TopWord {
+			m.TopWord, m.TopCount = word, count
+		}
+	}
+	return m
+}
+
+// writeMeta writes m as JSON to the file at path.
+func writeMeta(path string, m Meta) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// bufferHint points an err about a word or line too long for the buffer to
+// the -buffer flag that raises its size.
+func bufferHint(err error) error {
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("%w (raise -buffer to allow more)", err)
+	}
+	return err
+}
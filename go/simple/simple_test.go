@@ -0,0 +1,602 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/clmnin/wordcount/go/wordcount"
+)
+
+// runWith runs the command with args on stdin and returns what it wrote to
+// stdout and stderr, and its exit code.
+func runWith(t *testing.T, stdin string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return out.String(), errOut.String(), code
+}
+
+// writeFile writes content to name in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMergeIntoOutput(t *testing.T) {
+	saved := writeFile(t, "counts.txt", "b 2\na 1\n")
+	if _, stderr, code := runWith(t, "a b b c\n", "-workers", "1", "-merge", saved, "-o", saved); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	got, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b 4\na 2\nc 1\n"; string(got) != want {
+		t.Errorf("-merge and -o on the same file wrote %q, want %q", got, want)
+	}
+}
+
+func TestMergeSample(t *testing.T) {
+	saved := writeFile(t, "counts.txt", "b 3\n")
+	stdout, stderr, code := runWith(t, "a a a a a a a a\n", "-workers", "1", "-sample", "0.5", "-merge", saved)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	// the saved count is exact, only the new words are scaled up
+	if !strings.Contains(stdout, "b 3\n") {
+		t.Errorf("-sample scaled the -merge counts:\n%s", stdout)
+	}
+}
+
+func TestMaxDistinctFiles(t *testing.T) {
+	one := writeFile(t, "one.txt", "a b a\n")
+	two := writeFile(t, "two.txt", "c d e c\n")
+	// every run keeps the same words, the first three read
+	for i := 0; i < 10; i++ {
+		stdout, stderr, code := runWith(t, "", "-workers", "4", "-max-distinct", "3", one, two)
+		if code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr)
+		}
+		if want := "a 2\nc 2\nb 1\n"; stdout != want {
+			t.Fatalf("run %d: -max-distinct printed %q, want %q", i, stdout, want)
+		}
+	}
+}
+
+func TestMaxDistinctSample(t *testing.T) {
+	if _, _, code := runWith(t, "a b\n", "-max-distinct", "3", "-sample", "0.5"); code != 2 {
+		t.Errorf("-max-distinct with -sample exit code = %d, want 2", code)
+	}
+}
+
+func TestFirstPos(t *testing.T) {
+	stdout, stderr, code := runWith(t, "b a b c a\n", "-first-pos", "-tsv")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := "b\t1\na\t2\nc\t4\n"; stdout != want {
+		t.Errorf("-first-pos printed %q, want %q", stdout, want)
+	}
+}
+
+func TestFirstPosConflicts(t *testing.T) {
+	for _, flags := range [][]string{
+		{"-min", "2"}, {"-summary"}, {"-pct"}, {"-bars"}, {"-by-count"},
+		{"-top-per-length"}, {"-json"}, {"-csv"}, {"-asc"}, {"-sample", "0.5"},
+	} {
+		_, stderr, code := runWith(t, "a b\n", append([]string{"-first-pos"}, flags...)...)
+		if code != 2 || !strings.Contains(stderr, "-first-pos cannot be used with "+flags[0]) {
+			t.Errorf("-first-pos %v: exit code %d, %q, want 2 and a usage error", flags, code, stderr)
+		}
+	}
+}
+
+func TestUnescapeFormat(t *testing.T) {
+	tests := []struct{ format, want string }{
+		{`{{.Word}}\t{{.Count}}`, "{{.Word}}\t{{.Count}}"},
+		{`{{printf "%s\n" .Word}}\n`, `{{printf "%s\n" .Word}}` + "\n"},
+		{`a\\n {{.Word}`, "a\\n {{.Word}"},
+	}
+	for _, tt := range tests {
+		if got := unescapeFormat(tt.format); got != tt.want {
+			t.Errorf("unescapeFormat(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	stdout, stderr, code := runWith(t, "b a b\n", "-format", `{{printf "%s=%d\n" .Word .Count}}\t|`)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := "b=2\n\t|\na=1\n\t|\n"; stdout != want {
+		t.Errorf("-format printed %q, want %q", stdout, want)
+	}
+}
+
+func TestInputFilesSymlinkedDir(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real")
+	if err := os.MkdirAll(filepath.Join(target, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.md", filepath.Join("sub", "c.txt")} {
+		if err := os.WriteFile(filepath.Join(target, name), []byte("word\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip(err)
+	}
+	got := inputFiles([]string{link}, ".txt", func(err error) { t.Error(err) })
+	want := []string{filepath.Join(link, "a.txt"), filepath.Join(link, "sub", "c.txt")}
+	if !slices.Equal(got, want) {
+		t.Errorf("inputFiles(%q) = %q, want %q", link, got, want)
+	}
+}
+
+func TestApproxTopNote(t *testing.T) {
+	_, stderr, code := runWith(t, "a a b\n", "-quiet", "-approx-top", "1")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "estimates") {
+		t.Errorf("-quiet silenced the -approx-top note, stderr %q", stderr)
+	}
+}
+
+func TestTiesAlphabetical(t *testing.T) {
+	stdout, stderr, code := runWith(t, "c b a d c b a\n")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := "a 2\nb 2\nc 2\nd 1\n"; stdout != want {
+		t.Errorf("printed %q, want the words of equal count in alphabetical order %q", stdout, want)
+	}
+}
+
+func TestMin(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-min", "2"}, "a 3\nb 2\n"},
+		{[]string{"-min", "2", "-top", "1"}, "a 3\n"},
+		{[]string{"-min", "2", "-unique"}, "2\n"},
+		{[]string{"-min", "4"}, ""},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := runWith(t, "a b c a b a\n", append(tt.args, "-allow-empty")...)
+		if code != 0 {
+			t.Fatalf("%v: exit code %d: %s", tt.args, code, stderr)
+		}
+		if stdout != tt.want {
+			t.Errorf("%v printed %q, want %q", tt.args, stdout, tt.want)
+		}
+	}
+}
+
+func TestLoadStopWords(t *testing.T) {
+	path := writeFile(t, "stop.txt", "the\n\n  a  \nof\n")
+	got, err := loadStopWords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"the": true, "a": true, "of": true}
+	if !maps.Equal(got, want) {
+		t.Errorf("loadStopWords = %v, want %v", got, want)
+	}
+}
+
+func TestLang(t *testing.T) {
+	tests := []struct{ lang, input, want string }{
+		{"tr", "İstanbul ISPARTA\n", "istanbul 1\nısparta 1\n"},
+		// a final sigma lowers to ς
+		{"el", "ΟΔΟΣ\n", "οδος 1\n"},
+		{"en", "Hello\n", "hello 1\n"},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := runWith(t, tt.input, "-lang", tt.lang)
+		if code != 0 {
+			t.Fatalf("-lang %s: exit code %d: %s", tt.lang, code, stderr)
+		}
+		if stdout != tt.want {
+			t.Errorf("-lang %s printed %q, want %q", tt.lang, stdout, tt.want)
+		}
+	}
+	if _, _, code := runWith(t, "a\n", "-lang", "not a tag"); code != 2 {
+		t.Errorf("-lang with a bad tag exit code = %d, want 2", code)
+	}
+}
+
+func TestCaseSensitive(t *testing.T) {
+	stdout, _, _ := runWith(t, "Go go GO\n")
+	if want := "go 3\n"; stdout != want {
+		t.Errorf("default printed %q, want %q", stdout, want)
+	}
+	stdout, _, _ = runWith(t, "Go go GO\n", "-case-sensitive")
+	if want := "GO 1\nGo 1\ngo 1\n"; stdout != want {
+		t.Errorf("-case-sensitive printed %q, want %q", stdout, want)
+	}
+}
+
+func TestDecodeInput(t *testing.T) {
+	tests := []struct {
+		name, encoding, input, want string
+		err                         error
+	}{
+		{"plain", "auto", "héllo", "héllo", nil},
+		{"utf-8 bom", "auto", "\xef\xbb\xbfhéllo", "héllo", nil},
+		{"utf-16le bom", "auto", "\xff\xfeh\x00\xe9\x00", "hé", nil},
+		{"utf-16be bom", "auto", "\xfe\xff\x00h\x00\xe9", "hé", nil},
+		{"utf-16le forced", "utf-16le", "h\x00i\x00", "hi", nil},
+		{"surrogate pair", "utf-16le", "\x3d\xd8\x00\xde", "😀", nil},
+		{"unpaired surrogate", "utf-16be", "\xd8\x3d\x00a", "�a", nil},
+		{"odd byte", "utf-16le", "h\x00i", "h", io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		got, err := io.ReadAll(decodeInput(strings.NewReader(tt.input), tt.encoding))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: decodeInput read %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// byCount orders counts the way -sort count does by default.
+func byCount(a, b wordcount.Count) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Word < b.Word
+}
+
+// manyCounts returns n distinct words with counts that repeat, so there are ties.
+func manyCounts(n int) map[string]int {
+	counts := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		counts[fmt.Sprintf("w%d", i)] = i*7919%1000 + 1
+	}
+	return counts
+}
+
+// sortedCounts is the list topCounts saves building: every word sorted with before.
+func sortedCounts(counts map[string]int, minCount int, before func(a, b wordcount.Count) bool) []wordcount.Count {
+	ordered := make([]wordcount.Count, 0, len(counts))
+	for word, count := range counts {
+		if count >= minCount {
+			ordered = append(ordered, wordcount.Count{Word: word, Count: count})
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool { return before(ordered[i], ordered[j]) })
+	return ordered
+}
+
+func TestTopCounts(t *testing.T) {
+	counts := manyCounts(5000)
+	for _, k := range []int{1, 10, 100} {
+		for _, minCount := range []int{1, 990} {
+			want := sortedCounts(counts, minCount, byCount)
+			if len(want) > k {
+				want = want[:k]
+			}
+			got := topCounts(counts, nil, k, minCount, byCount)
+			if !slices.Equal(got, want) {
+				t.Errorf("topCounts k %d min %d = %v, want %v", k, minCount, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkTopCounts(b *testing.B) {
+	counts := manyCounts(100000)
+	b.Run("heap", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			topCounts(counts, nil, 10, 1, byCount)
+		}
+	})
+	b.Run("sort", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = sortedCounts(counts, 1, byCount)[:10]
+		}
+	})
+}
+
+func TestDelimiterTab(t *testing.T) {
+	stdout, stderr, code := runWith(t, "new york\tparis\nnew york\n", "-delimiter", `\t`)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := "new york 2\nparis 1\n"; stdout != want {
+		t.Errorf("-delimiter \\t printed %q, want %q", stdout, want)
+	}
+	if _, _, code := runWith(t, "", "-delimiter", "ab"); code != 2 {
+		t.Errorf("-delimiter ab exit code = %d, want 2", code)
+	}
+}
+
+func TestTokenRegexLongLine(t *testing.T) {
+	// no word is long, but -token-regex matches whole lines
+	line := strings.Repeat("ab ", 40000) + "\n"
+	_, stderr, code := runWith(t, line, "-token-regex", "[a-z]+")
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr, "line longer than") || !strings.Contains(stderr, "-buffer") {
+		t.Errorf("stderr = %q, want a line too long and a hint for -buffer", stderr)
+	}
+	stdout, stderr, code := runWith(t, line, "-token-regex", "[a-z]+", "-buffer", "1000000")
+	if code != 0 {
+		t.Fatalf("-buffer 1000000 exit code %d: %s", code, stderr)
+	}
+	if want := "ab 40000\n"; stdout != want {
+		t.Errorf("-buffer 1000000 printed %q, want %q", stdout, want)
+	}
+}
+
+func TestDupes(t *testing.T) {
+	stdout, stderr, code := runWith(t, "a a b b b c\n", "-dupes")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := "b: 2 consecutive repeats\na: 1 consecutive repeats\n"; stdout != want {
+		t.Errorf("-dupes printed %q, want %q", stdout, want)
+	}
+}
+
+func TestTail(t *testing.T) {
+	stdout, stderr, code := runWith(t, "a a a b b c d\n", "-tail", "3")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	// the least frequent words, still in the order of the full list
+	if want := "b 2\nc 1\nd 1\n"; stdout != want {
+		t.Errorf("-tail 3 printed %q, want %q", stdout, want)
+	}
+	// the least frequent words whatever the order of the list
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-asc", "-tail", "3"}, "c 1\nd 1\nb 2\n"},
+		{[]string{"-sort", "word", "-tail", "2"}, "c 1\nd 1\n"},
+	} {
+		stdout, stderr, code := runWith(t, "a a a b b c d\n", tc.args...)
+		if code != 0 {
+			t.Fatalf("%v exit code %d: %s", tc.args, code, stderr)
+		}
+		if stdout != tc.want {
+			t.Errorf("%v printed %q, want %q", tc.args, stdout, tc.want)
+		}
+	}
+	if _, _, code := runWith(t, "a\n", "-tail", "1", "-top", "1"); code != 2 {
+		t.Errorf("-tail with -top exit code = %d, want 2", code)
+	}
+}
+
+func TestManyFiles(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i, text := range []string{"b a\n", "c b\n", "a d\n"} {
+		path := filepath.Join(dir, fmt.Sprintf("%d.txt", i))
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{files, "a 2\nb 2\nc 1\nd 1\n"},
+		// the words of the first file are seen first, whichever file is counted first
+		{append([]string{"-tie", "first-seen"}, files...), "b 2\na 2\nc 1\nd 1\n"},
+		{[]string{dir}, "a 2\nb 2\nc 1\nd 1\n"},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 5; i++ {
+			stdout, stderr, code := runWith(t, "", append([]string{"-workers", "3"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("exit code %d: %s", code, stderr)
+			}
+			if stdout != tt.want {
+				t.Fatalf("run %d of %v printed %q, want %q", i, tt.args, stdout, tt.want)
+			}
+		}
+	}
+}
+
+func TestLowercaseFalse(t *testing.T) {
+	stdout, _, _ := runWith(t, "Go go\n", "-lowercase=false")
+	if want := "Go 1\ngo 1\n"; stdout != want {
+		t.Errorf("-lowercase=false printed %q, want %q", stdout, want)
+	}
+}
+
+func TestTie(t *testing.T) {
+	const input = "b c a c b a d\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-tie", "alpha"}, "a 2\nb 2\nc 2\nd 1\n"},
+		{[]string{"-tie", "first-seen"}, "b 2\nc 2\na 2\nd 1\n"},
+		{[]string{"-tie", "first-seen", "-top", "2"}, "b 2\nc 2\n"},
+		{[]string{"-tie", "first-seen", "-sort", "word"}, "a 2\nb 2\nc 2\nd 1\n"},
+	}
+	for _, tt := range tests {
+		stdout, stderr, code := runWith(t, input, tt.args...)
+		if code != 0 {
+			t.Fatalf("%v: exit code %d: %s", tt.args, code, stderr)
+		}
+		if stdout != tt.want {
+			t.Errorf("%v printed %q, want %q", tt.args, stdout, tt.want)
+		}
+	}
+	if _, _, code := runWith(t, input, "-tie", "random"); code != 2 {
+		t.Errorf("-tie random exit code = %d, want 2", code)
+	}
+	if _, _, code := runWith(t, input, "-tie", "first-seen", "-approx-top", "2"); code != 2 {
+		t.Errorf("-tie first-seen with -approx-top exit code = %d, want 2", code)
+	}
+}
+
+// writeCounter counts the Write calls made on it.
+type writeCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestOutputBuffered(t *testing.T) {
+	input := strings.Repeat("w1 w2 w3 w4 w5 w6 w7 w8 w9 w10\n", 100)
+	var out writeCounter
+	if code := run(nil, strings.NewReader(input), &out, io.Discard); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if !strings.HasPrefix(out.String(), "w1 100\nw10 100\n") {
+		t.Errorf("printed %q", out.String())
+	}
+	// ten lines fit in one buffer
+	if out.writes != 1 {
+		t.Errorf("the word list took %d writes, want 1", out.writes)
+	}
+}
+
+func BenchmarkWriteText(b *testing.B) {
+	counts := manyCounts(100000)
+	ordered := sortedCounts(counts, 1, byCount)
+	// a file, so each unbuffered line is a system call as on a real stdout
+	f, err := os.Create(os.DevNull)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	b.Run("unbuffered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := writeText(f, ordered, false, 0, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("buffered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			buf := bufio.NewWriter(f)
+			if err := writeText(buf, ordered, false, 0, false); err != nil {
+				b.Fatal(err)
+			}
+			if err := buf.Flush(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestWriteTopPerLength(t *testing.T) {
+	counts := map[string]int{"a": 3, "i": 3, "to": 2, "of": 5, "the": 4, "café": 1, "word": 1, "rare": 0}
+	var out bytes.Buffer
+	if err := writeTopPerLength(&out, counts, 1); err != nil {
+		t.Fatal(err)
+	}
+	// ties go to the first word alphabetically, lengths are in characters
+	want := "1: a (3)\n2: of (5)\n3: the (4)\n4: café (1)\n"
+	if out.String() != want {
+		t.Errorf("writeTopPerLength wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestMergeIntoOutputFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		stdin string
+	}{
+		{"corrupt gzip", "\x1f\x8bnot gzip"},
+		{"word too long", strings.Repeat("x", 100*1024) + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := writeFile(t, "agg.txt", "a 100\n")
+			if _, _, code := runWith(t, tt.stdin, "-workers", "1", "-merge", saved, "-o", saved); code != 1 {
+				t.Errorf("exit code %d, want 1", code)
+			}
+			got, err := os.ReadFile(saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "a 100\n" {
+				t.Errorf("a failed run left -o holding %q, want the saved counts", got)
+			}
+			// the temporary file is cleaned up
+			if entries, _ := os.ReadDir(filepath.Dir(saved)); len(entries) != 1 {
+				t.Errorf("the output directory holds %d files, want 1", len(entries))
+			}
+		})
+	}
+}
+
+func TestApproxTopFiles(t *testing.T) {
+	one := writeFile(t, "one.txt", "a a a b\n")
+	two := writeFile(t, "two.txt", "c c d\n")
+	three := writeFile(t, "three.txt", "e a\n")
+	stdout, stderr, code := runWith(t, "", "-workers", "3", "-approx-top", "2", one, two, three)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	// the tops of the three files are merged and cut back to K
+	if want := "a 4\nc 2\n"; stdout != want {
+		t.Errorf("-approx-top 2 over three files printed %q, want %q", stdout, want)
+	}
+}
+
+func TestFilesFrom(t *testing.T) {
+	one := writeFile(t, "one.txt", "a b\n")
+	two := writeFile(t, "two.txt", "b c\n")
+	extra := writeFile(t, "extra.txt", "c d\n")
+	manifest := writeFile(t, "list.txt", "# inputs\n"+one+"\n\n  "+two+"  \n")
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+	}{
+		{"manifest", "", []string{"-files-from", manifest}, "b 2\na 1\nc 1\n"},
+		{"stdin", one + "\n" + two + "\n", []string{"-files-from", "-"}, "b 2\na 1\nc 1\n"},
+		{"with arguments", "", []string{"-files-from", manifest, extra}, "b 2\nc 2\na 1\nd 1\n"},
+		// an empty list counts nothing rather than reading stdin for words
+		{"empty", "# nothing\n", []string{"-files-from", "-", "-allow-empty"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runWith(t, tt.stdin, tt.args...)
+			if code != 0 {
+				t.Fatalf("exit code %d: %s", code, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("printed %q, want %q", stdout, tt.want)
+			}
+		})
+	}
+}
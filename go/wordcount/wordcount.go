@@ -0,0 +1,1154 @@
+// Package wordcount counts how many times each word occurs in a text. It is
+// the counting core of the simple-go command, and Options holds the same
+// choices as its flags.
+package wordcount
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Options controls how CountWords turns the input into words.
+type Options struct {
+	// Chars counts single characters (runes) instead of words. Whitespace is
+	// not counted. It takes precedence over Delimiter and TokenRegex.
+	Chars bool
+	// Delimiter, when set, makes the words the fields between Delimiter
+	// characters and line ends, turning the count into one of field values.
+	// Empty fields are skipped. It takes precedence over TokenRegex.
+	Delimiter rune
+	// TrimFields trims the whitespace around each Delimiter field.
+	TrimFields bool
+	// TokenRegex, when set, defines the words as the matches of the regular
+	// expression on each line instead of the runs of non-space characters.
+	TokenRegex *regexp.Regexp
+	// StripPunct trims leading and trailing punctuation from each word,
+	// keeping interior punctuation such as the apostrophe in "don't".
+	StripPunct bool
+	// SplitHyphens counts each part of a hyphenated word, such as
+	// "state-of-the-art", as a word of its own. It applies after StripPunct.
+	SplitHyphens bool
+	// NoNumbers skips words made only of digits. Words that mix digits and
+	// letters, such as "3rd", are still counted.
+	NoNumbers bool
+	// Lowercase lowercases the words before they are counted. When it is not
+	// set words are counted as they are written, and Fold and Lang are
+	// ignored.
+	Lowercase bool
+	// Fold groups words by Unicode case folding rather than lowercasing, so
+	// that for example "Straße" and "STRASSE" are counted as "strasse".
+	Fold bool
+	// Lang, when set, is the language whose rules are used for lowercasing,
+	// such as the dotless ı of Turkish or the final ς of Greek. The default,
+	// language.Und, is strings.ToLower.
+	Lang language.Tag
+	// StopWords holds words that are not counted. Words are looked up after
+	// lowercasing, so with Lowercase set the set should hold lowercase
+	// words.
+	StopWords map[string]bool
+	// Include, when set, skips the words it does not match. Like StopWords it
+	// is checked after lowercasing.
+	Include *regexp.Regexp
+	// MinLen and MaxLen skip words with fewer or more characters (runes, not
+	// bytes). A MaxLen of 0 means no upper limit.
+	MinLen, MaxLen int
+	// Stem replaces each word by its Porter stem once it has passed the
+	// filters above, so "running" and "runs" are both counted as "run".
+	Stem bool
+	// NGram counts runs of NGram consecutive words, joined by single spaces,
+	// instead of single words. Values of 1 or less count single words.
+	NGram int
+	// Cooccur, when above 1, counts pairs of different words that appear
+	// within Cooccur consecutive words instead of single words. Each pair is
+	// keyed by its two words in sorted order joined by a space, and is
+	// counted at most once for each word that closes a window.
+	Cooccur int
+	// Dupes counts only the words that are the same as the word just before
+	// them, after lowercasing, so "the the the" counts "the" twice.
+	Dupes bool
+	// FirstPos records, in place of each word's count, the position of its
+	// first occurrence among the words counted, starting at 1. It forces a
+	// serial count.
+	FirstPos bool
+	// Tokenizer, when set, makes the Tokenizer that splits each input into
+	// words, in place of the one picked from Chars, Delimiter and
+	// TokenRegex. It forces a serial count.
+	Tokenizer func(r io.Reader) Tokenizer
+	// MaxTokenSize is the longest word, in bytes, that can be read. Zero means
+	// bufio.MaxScanTokenSize; longer words make CountWords return an error.
+	MaxTokenSize int
+	// ApproxTop, when above 0, keeps only an estimate of the ApproxTop most
+	// frequent words rather than counting every distinct word. Memory stays
+	// at about 1MiB for the sketch plus the ApproxTop words, however big the
+	// vocabulary, for each input counted at the same time; see topSketch for
+	// the accuracy this buys. Each input gets a sketch of its own, so the
+	// counts of several inputs hold up to ApproxTop words from each of them.
+	ApproxTop int
+	// Sample, when between 0 and 1, counts each word only with probability
+	// Sample, for a quick estimate of a big input. The counts are of the
+	// words sampled; ScaleCounts turns them into estimates for the input.
+	// The choices come from a math/rand source seeded with Seed, so the same
+	// input, Sample and Seed always give the same counts. It forces a serial
+	// count.
+	Sample float64
+	Seed   int64
+	// MaxDistinct, when above 0, stops new words from being added once the
+	// counts hold MaxDistinct words, bounding their memory on untrusted
+	// input. Words already counted keep counting. It forces a serial count.
+	MaxDistinct int
+	// Seen, when set, is called with each word the first time it is added to
+	// the counts, so callers can keep the order the words appear in. It forces
+	// a serial count. It is not called with ApproxTop, as the sketch keeps no
+	// set of the words already seen.
+	Seen func(word string)
+	// LimitReached, when set, is called when MaxDistinct first keeps a new
+	// word out of the counts for an input.
+	LimitReached func()
+	// Workers is the number of goroutines that count the input in parallel.
+	// Values of 1 or less count serially.
+	Workers int
+	// SkipInvalid skips the words that are not valid UTF-8 instead of
+	// counting them. With Chars each byte that is not part of a valid
+	// character is such a word.
+	SkipInvalid bool
+	// Invalid, when set, is called for each word that is not valid UTF-8,
+	// whether or not it is skipped. With Workers above 1 it is called from
+	// several goroutines.
+	Invalid func()
+	// Progress, when set, is called with the number of words counted since
+	// the last call, every ProgressEvery words and once more when the input
+	// runs out. With Workers above 1 it is called from several goroutines.
+	Progress func(n int)
+	// Done, when set, stops the counting once it is closed, and CountWords
+	// returns the words counted so far with ErrStopped. It is checked between
+	// words, so a read that is waiting for input is not cut short.
+	Done <-chan struct{}
+}
+
+// stopCheckEvery is how many tokens are read between checks of Options.Done.
+const stopCheckEvery = 1024
+
+// stopped reports whether done has been closed. A nil done is never closed.
+func stopped(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// ErrStopped is returned by CountWords, along with the words counted so far,
+// when Options.Done is closed before the input runs out.
+var ErrStopped = errors.New("counting stopped")
+
+// ProgressEvery is how many words are counted between calls to
+// Options.Progress.
+const ProgressEvery = 100000
+
+// CountWords reads r word by word and returns how many times each word
+// occurs, lowercased when opts.Lowercase is set, along with any error from
+// reading r.
+func CountWords(r io.Reader, opts Options) (map[string]int, error) {
+	if opts.parallel() {
+		return countParallel(r, opts)
+	}
+	counts := make(map[string]int)
+	err := countInto(counts, r, opts)
+	return counts, err
+}
+
+// CountWordsInto is CountWords adding the words of r to counts, which may
+// already hold words from other inputs. MaxDistinct then limits the distinct
+// words of counts as a whole, and as the words are added in the order they
+// are read the same ones are kept on every run.
+func CountWordsInto(counts map[string]int, r io.Reader, opts Options) error {
+	if opts.parallel() {
+		partial, err := countParallel(r, opts)
+		MergeCounts(counts, partial)
+		return err
+	}
+	return countInto(counts, r, opts)
+}
+
+// parallel reports whether the input can be split between opts.Workers
+// goroutines.
+func (opts Options) parallel() bool {
+	if opts.Workers <= 1 {
+		return false
+	}
+	// n-grams, co-occurrence windows, repeats and first positions run across
+	// chunk boundaries, and a custom Tokenizer may not be safe to start in
+	// the middle of the input
+	if opts.NGram > 1 || opts.Cooccur > 1 || opts.Dupes || opts.FirstPos || opts.Tokenizer != nil {
+		return false
+	}
+	// samples must follow a single random sequence to be repeatable, a sketch
+	// or a limit on distinct words is not split between workers, and Seen
+	// would be called out of order
+	return !opts.sampled() && opts.ApproxTop <= 0 && opts.MaxDistinct <= 0 && opts.Seen == nil
+}
+
+// countInto is the serial counting loop, adding the words of r to counts.
+func countInto(counts map[string]int, r io.Reader, opts Options) error {
+	var tok Tokenizer
+	if opts.Tokenizer != nil {
+		tok = opts.Tokenizer(r)
+	} else {
+		tok = newScanTokenizer(r, opts)
+	}
+	c := newCounter(counts, opts)
+	tokens := 0
+	var stopErr error
+	// read to the next token, as the Tokenizer defines them
+	for {
+		word, ok := tok.Next()
+		if !ok {
+			break
+		}
+		tokens++
+		if tokens%stopCheckEvery == 0 && stopped(opts.Done) {
+			stopErr = ErrStopped
+			break
+		}
+		if !utf8.ValidString(word) {
+			if opts.Invalid != nil {
+				opts.Invalid()
+			}
+			if opts.SkipInvalid {
+				continue
+			}
+		}
+		if opts.StripPunct {
+			word = strings.TrimFunc(word, unicode.IsPunct)
+			// a token made only of punctuation is not a word
+			if word == "" {
+				continue
+			}
+		}
+		if opts.SplitHyphens {
+			for _, part := range strings.FieldsFunc(word, isHyphen) {
+				c.add(part)
+			}
+			continue
+		}
+		c.add(word)
+	}
+	c.flush()
+	// Done may be closed while the last read blocked, such as on a terminal
+	if stopErr == nil && stopped(opts.Done) {
+		stopErr = ErrStopped
+	}
+	if stopErr != nil {
+		return stopErr
+	}
+	return tok.Err()
+}
+
+// Tokenizer splits an input into the tokens that are counted as words.
+type Tokenizer interface {
+	// Next returns the next token, or false once there are no more.
+	Next() (string, bool)
+	// Err returns the error that ended the tokens early, if any.
+	Err() error
+}
+
+// scanRunes is bufio.ScanRunes returning a byte that is not valid UTF-8 as it
+// is, instead of as the U+FFFD it stands for, so that SkipInvalid can tell it
+// from a U+FFFD in the input.
+func scanRunes(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanRunes(data, atEOF)
+	if advance == 1 && len(token) > 1 {
+		return advance, data[:1], err
+	}
+	return advance, token, err
+}
+
+// scanTokenizer is the Tokenizer for Options that do not set one: a
+// bufio.Scanner splitting on whitespace, runes, Delimiter or TokenRegex.
+type scanTokenizer struct {
+	scanner *bufio.Scanner
+	opts    Options
+}
+
+func newScanTokenizer(r io.Reader, opts Options) *scanTokenizer {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	switch {
+	case opts.Chars:
+		scanner.Split(scanRunes)
+	case opts.Delimiter != 0:
+		scanner.Split(delimiterSplit(opts.Delimiter))
+	case opts.TokenRegex != nil:
+		scanner.Split(regexSplit(opts.TokenRegex))
+	}
+	if opts.MaxTokenSize > 0 {
+		scanner.Buffer(nil, opts.MaxTokenSize)
+	}
+	return &scanTokenizer{scanner: scanner, opts: opts}
+}
+
+func (t *scanTokenizer) Next() (string, bool) {
+	opts := t.opts
+	for t.scanner.Scan() {
+		word := t.scanner.Text()
+		if opts.Chars && isSpace(word) {
+			continue
+		}
+		if opts.Delimiter != 0 {
+			word = strings.TrimSuffix(word, "\r")
+			if opts.TrimFields {
+				word = strings.TrimSpace(word)
+			}
+			if word == "" {
+				continue
+			}
+		}
+		return word, true
+	}
+	return "", false
+}
+
+func (t *scanTokenizer) Err() error {
+	return tooLong(t.scanner.Err(), t.opts)
+}
+
+// tooLong adds to a bufio.ErrTooLong err what did not fit in a buffer of
+// opts.MaxTokenSize: a word, or a whole line, as TokenRegex matches lines
+// that must each fit in the buffer. Other errors are returned as they are.
+func tooLong(err error, opts Options) error {
+	if !errors.Is(err, bufio.ErrTooLong) {
+		return err
+	}
+	limit := opts.MaxTokenSize
+	if limit <= 0 {
+		limit = bufio.MaxScanTokenSize
+	}
+	if !opts.Chars && opts.Delimiter == 0 && opts.TokenRegex != nil {
+		return fmt.Errorf("found a line longer than %d bytes, and the token regular expression is matched line by line: %w", limit, err)
+	}
+	return fmt.Errorf("found a word longer than %d bytes: %w", limit, err)
+}
+
+// isSpace reports whether the single character s is whitespace.
+func isSpace(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(r)
+}
+
+// isNumber reports whether word is made only of digits.
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return word != ""
+}
+
+// ScaleCounts turns counts taken from a sample of probability p into
+// estimates for the whole input, rounded to the nearest whole count.
+func ScaleCounts(counts map[string]int, p float64) {
+	for word, count := range counts {
+		counts[word] = int(math.Round(float64(count) / p))
+	}
+}
+
+// isHyphen reports whether r is one of the Unicode hyphens, such as '-'.
+func isHyphen(r rune) bool {
+	return unicode.Is(unicode.Hyphen, r)
+}
+
+// counter adds words to a counts map, applying the lowercasing and filtering
+// of its Options and keeping the state that carries over from word to word.
+type counter struct {
+	counts map[string]int
+	opts   Options
+	// caser folds the words for Options.Fold, or lowercases them for
+	// Options.Lang
+	caser *cases.Caser
+	// window is a ring buffer of the last NGram words, seen counts the words put in it
+	window []string
+	seen   int
+	// prev is the word before, for Options.Dupes
+	prev string
+	// pos is the position of the last word counted, for Options.FirstPos
+	pos int
+	// rng picks the words counted for Options.Sample
+	rng *rand.Rand
+	// unreported is the number of words counted since the last Progress call
+	unreported int
+	// sketch, set for Options.ApproxTop, takes the words instead of counts
+	sketch *topSketch
+	// limited is set once Options.MaxDistinct has kept a word out
+	limited bool
+}
+
+func newCounter(counts map[string]int, opts Options) *counter {
+	c := &counter{counts: counts, opts: opts}
+	// a Caser keeps state, so each counter has its own
+	switch {
+	case opts.Fold:
+		caser := cases.Fold()
+		c.caser = &caser
+	case opts.Lang != language.Und:
+		caser := cases.Lower(opts.Lang)
+		c.caser = &caser
+	}
+	if opts.NGram > 1 {
+		c.window = make([]string, opts.NGram)
+	} else if opts.Cooccur > 1 {
+		c.window = make([]string, opts.Cooccur)
+	}
+	if opts.ApproxTop > 0 {
+		c.sketch = newTopSketch(opts.ApproxTop)
+	}
+	if opts.sampled() {
+		c.rng = rand.New(rand.NewSource(opts.Seed))
+	}
+	return c
+}
+
+// add counts one word from the input.
+func (c *counter) add(word string) {
+	opts := c.opts
+	if opts.NoNumbers && isNumber(word) {
+		return
+	}
+	// lower case the text and increase the count at HashMap
+	switch {
+	case !opts.Lowercase:
+	case c.caser != nil:
+		word = c.caser.String(word)
+	default:
+		word = strings.ToLower(word)
+	}
+	// stop words are dropped here so they never take up room in the map
+	if opts.StopWords[word] {
+		return
+	}
+	if opts.Include != nil && !opts.Include.MatchString(word) {
+		return
+	}
+	if opts.MinLen > 0 || opts.MaxLen > 0 {
+		n := utf8.RuneCountInString(word)
+		if n < opts.MinLen || opts.MaxLen > 0 && n > opts.MaxLen {
+			return
+		}
+	}
+	if opts.Stem {
+		word = porterStem(word)
+	}
+	switch {
+	case opts.Cooccur > 1:
+		c.addPairs(word)
+	case opts.Dupes:
+		if word == c.prev {
+			c.count(word)
+		}
+		c.prev = word
+	case c.window != nil:
+		c.window[c.seen%len(c.window)] = word
+		c.seen++
+		if c.seen < len(c.window) {
+			return
+		}
+		c.count(joinWindow(c.window, c.seen))
+	default:
+		c.count(word)
+	}
+	if opts.Progress != nil {
+		c.unreported++
+		if c.unreported == ProgressEvery {
+			opts.Progress(c.unreported)
+			c.unreported = 0
+		}
+	}
+}
+
+// addPairs counts word paired with each different word among the words before
+// it in the window, then puts word in the window. A pair found twice in the
+// window is still only counted once.
+func (c *counter) addPairs(word string) {
+	n := len(c.window) - 1
+	if c.seen < n {
+		n = c.seen
+	}
+	for i := 1; i <= n; i++ {
+		other := c.window[(c.seen-i)%len(c.window)]
+		if other == word || repeatsIn(c.window, c.seen, i, other) {
+			continue
+		}
+		if other < word {
+			c.count(other + " " + word)
+		} else {
+			c.count(word + " " + other)
+		}
+	}
+	c.window[c.seen%len(c.window)] = word
+	c.seen++
+}
+
+// repeatsIn reports whether word is among the i-1 newest words of the ring
+// buffer window, where seen is the number of words put in it so far.
+func repeatsIn(window []string, seen, i int, word string) bool {
+	for j := 1; j < i; j++ {
+		if window[(seen-j)%len(window)] == word {
+			return true
+		}
+	}
+	return false
+}
+
+// count adds one to the count of word, or hands it to the sketch. With
+// Options.Sample only some of the words are counted.
+func (c *counter) count(word string) {
+	opts := c.opts
+	c.pos++
+	if c.rng != nil && c.rng.Float64() >= opts.Sample {
+		return
+	}
+	if opts.Seen != nil && c.sketch == nil {
+		if _, ok := c.counts[word]; !ok && c.room() {
+			opts.Seen(word)
+		}
+	}
+	switch {
+	case c.sketch != nil:
+		c.sketch.add(word)
+	case opts.FirstPos:
+		if _, ok := c.counts[word]; !ok && c.room() {
+			c.counts[word] = c.pos
+		}
+	case opts.MaxDistinct > 0:
+		if _, ok := c.counts[word]; ok || c.room() {
+			c.counts[word]++
+		}
+	default:
+		c.counts[word]++
+	}
+}
+
+// room reports whether Options.MaxDistinct leaves room for a new word,
+// calling Options.LimitReached the first time it does not.
+func (c *counter) room() bool {
+	if c.opts.MaxDistinct <= 0 || len(c.counts) < c.opts.MaxDistinct {
+		return true
+	}
+	if !c.limited {
+		c.limited = true
+		if c.opts.LimitReached != nil {
+			c.opts.LimitReached()
+		}
+	}
+	return false
+}
+
+// flush reports the words counted since the last Progress call, and adds the
+// estimates of the words kept by the sketch, if any, to the counts map.
+func (c *counter) flush() {
+	if c.opts.Progress != nil && c.unreported > 0 {
+		c.opts.Progress(c.unreported)
+		c.unreported = 0
+	}
+	if c.sketch != nil {
+		for _, top := range c.sketch.top.items {
+			c.counts[top.Word] += top.Count
+		}
+	}
+}
+
+// The count-min sketch is sketchDepth rows of sketchWidth counters.
+const (
+	sketchDepth = 4
+	sketchWidth = 1 << 16
+)
+
+// topSketch estimates the k most frequent words of a stream in fixed memory.
+// Each word bumps one counter per row of a count-min sketch and its estimate
+// is the smallest of those counters, which is never below the true count.
+// With conservative updates, only the smallest counters are bumped, which
+// keeps the overestimate low. For a stream of n words an estimate is within
+// e*n/sketchWidth, about n/24000, of the true count with probability
+// 1-e^-sketchDepth, about 98%. The words with the k highest estimates so far
+// are kept in a min-heap, so heavy hitters are found even when they first
+// show up late, while rare words can at worst push a word out of the heap
+// when their estimate is inflated by collisions.
+type topSketch struct {
+	rows [sketchDepth][]uint32
+	top  sketchHeap
+	k    int
+}
+
+func newTopSketch(k int) *topSketch {
+	s := &topSketch{k: k, top: sketchHeap{pos: make(map[string]int)}}
+	for i := range s.rows {
+		s.rows[i] = make([]uint32, sketchWidth)
+	}
+	return s
+}
+
+// add counts one occurrence of word.
+func (s *topSketch) add(word string) {
+	est := s.increment(word)
+	if i, ok := s.top.pos[word]; ok {
+		s.top.items[i].Count = est
+		heap.Fix(&s.top, i)
+		return
+	}
+	if len(s.top.items) < s.k {
+		heap.Push(&s.top, Count{Word: word, Count: est})
+		return
+	}
+	if est > s.top.items[0].Count {
+		// word now beats the least frequent of the top k, which is dropped
+		delete(s.top.pos, s.top.items[0].Word)
+		s.top.items[0] = Count{Word: word, Count: est}
+		s.top.pos[word] = 0
+		heap.Fix(&s.top, 0)
+	}
+}
+
+// increment bumps the counters of word with a conservative update and
+// returns the new estimate of its count.
+func (s *topSketch) increment(word string) int {
+	// FNV-1a, split in two halves for double hashing across the rows
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(word); i++ {
+		h ^= uint64(word[i])
+		h *= 1099511628211
+	}
+	h1, h2 := uint32(h), uint32(h>>32)|1
+	var idx [sketchDepth]uint32
+	est := uint32(math.MaxUint32)
+	for i := range s.rows {
+		idx[i] = (h1 + uint32(i)*h2) % sketchWidth
+		est = min(est, s.rows[i][idx[i]])
+	}
+	est++
+	for i := range s.rows {
+		if s.rows[i][idx[i]] < est {
+			s.rows[i][idx[i]] = est
+		}
+	}
+	return int(est)
+}
+
+// sketchHeap is a min-heap of the current top words by estimated count, with
+// pos tracking where each word is so its estimate can be updated in place. On
+// equal counts the alphabetically last word is dropped first.
+type sketchHeap struct {
+	items []Count
+	pos   map[string]int
+}
+
+func (h *sketchHeap) Len() int { return len(h.items) }
+func (h *sketchHeap) Less(i, j int) bool {
+	if h.items[i].Count != h.items[j].Count {
+		return h.items[i].Count < h.items[j].Count
+	}
+	return h.items[i].Word > h.items[j].Word
+}
+func (h *sketchHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.pos[h.items[i].Word] = i
+	h.pos[h.items[j].Word] = j
+}
+func (h *sketchHeap) Push(x any) {
+	c := x.(Count)
+	h.pos[c.Word] = len(h.items)
+	h.items = append(h.items, c)
+}
+func (h *sketchHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	delete(h.pos, last.Word)
+	return last
+}
+
+// lineBased reports whether the tokens can hold whitespace, so the input may
+// only be cut at line ends: fields for Delimiter and matches for TokenRegex.
+func (opts Options) lineBased() bool {
+	return !opts.Chars && (opts.Delimiter != 0 || opts.TokenRegex != nil)
+}
+
+// sampled reports whether only a sample of the words is counted.
+func (opts Options) sampled() bool {
+	return opts.Sample > 0 && opts.Sample < 1
+}
+
+// delimiterSplit returns a bufio.SplitFunc that returns the fields between
+// delim characters and newlines as the tokens, including empty fields.
+func delimiterSplit(delim rune) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		for i := 0; i < len(data); {
+			r, width := utf8.DecodeRune(data[i:])
+			if r == delim || r == '\n' {
+				return i + width, data[:i], nil
+			}
+			i += width
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// regexSplit returns a bufio.SplitFunc that reads the input line by line and
+// returns the non-empty matches of re in each line as the tokens.
+func regexSplit(re *regexp.Regexp) bufio.SplitFunc {
+	// pending holds the offsets, from the start of the current line, of the
+	// matches in it that have not been returned yet. Each match consumes the
+	// line up to its end and the last one the rest of the line, so every token
+	// advances the input, which the scanner insists on at EOF.
+	var pending [][]int
+	// start is the offset of data from the start of the current line, and
+	// lineLen the length of the line with its line end
+	var start, lineLen int
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if len(pending) == 0 {
+			skipped := 0
+			for {
+				advance, line, err := bufio.ScanLines(data[skipped:], atEOF)
+				if err != nil || advance == 0 {
+					return skipped, nil, err
+				}
+				for _, m := range re.FindAllIndex(line, -1) {
+					if m[1] > m[0] {
+						pending = append(pending, m)
+					}
+				}
+				if len(pending) > 0 {
+					start, lineLen = -skipped, advance
+					break
+				}
+				skipped += advance
+			}
+		}
+		m := pending[0]
+		pending = pending[1:]
+		end := m[1]
+		if len(pending) == 0 {
+			end = lineLen
+		}
+		token := data[m[0]-start : m[1]-start]
+		advance := end - start
+		start = end
+		return advance, token, nil
+	}
+}
+
+// joinWindow joins the words of the ring buffer window from oldest to newest,
+// where seen is the number of words put in it so far.
+func joinWindow(window []string, seen int) string {
+	var b strings.Builder
+	for i := range window {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(window[(seen+i)%len(window)])
+	}
+	return b.String()
+}
+
+// chunkSize is how much input is read before handing a chunk to a worker.
+const chunkSize = 64 * 1024
+
+// countParallel splits r into chunks that end on ASCII whitespace, so no word
+// spans two chunks, and counts them on opts.Workers goroutines. Each worker
+// keeps its own map and the maps are merged at the end, which gives the same
+// totals as counting serially.
+func countParallel(r io.Reader, opts Options) (map[string]int, error) {
+	chunks := make(chan []byte, opts.Workers)
+	partial := make([]map[string]int, opts.Workers)
+	errs := make([]error, opts.Workers)
+	var wg sync.WaitGroup
+	for i := range partial {
+		partial[i] = make(map[string]int)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for chunk := range chunks {
+				if err := countInto(partial[i], bytes.NewReader(chunk), opts); err != nil && errs[i] == nil {
+					errs[i] = err
+				}
+			}
+		}(i)
+	}
+
+	// a word, or a line when the cut is at line ends, may be no longer than
+	// the scanner of a serial count allows
+	limit := opts.MaxTokenSize
+	if limit <= 0 {
+		limit = bufio.MaxScanTokenSize
+	}
+	var readErr error
+	// buf holds the bytes after the last cut, followed by those read since;
+	// the first searched bytes of it hold no place to cut
+	buf := make([]byte, 0, chunkSize)
+	searched := 0
+	for {
+		if stopped(opts.Done) {
+			readErr = ErrStopped
+			break
+		}
+		if searched > limit {
+			readErr = tooLong(bufio.ErrTooLong, opts)
+			break
+		}
+		// grow buf by doubling, so a long run without a cut is copied only a
+		// few times
+		if cap(buf)-len(buf) < chunkSize {
+			grown := make([]byte, len(buf), 2*cap(buf)+chunkSize)
+			copy(grown, buf)
+			buf = grown
+		}
+		n, err := io.ReadFull(r, buf[len(buf):len(buf)+chunkSize])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if len(buf) > 0 {
+				chunks <- buf
+			}
+			if stopped(opts.Done) {
+				readErr = ErrStopped
+			}
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+		cut := lastSpace(buf[searched:])
+		if opts.lineBased() {
+			cut = bytes.LastIndexByte(buf[searched:], '\n')
+		}
+		if cut < 0 {
+			// No place to cut yet, so the whole buffer is one word; keep reading.
+			searched = len(buf)
+			continue
+		}
+		cut += searched
+		// the chunk now belongs to a worker, so the rest goes to a new buffer
+		chunks <- buf[:cut+1]
+		rest := buf[cut+1:]
+		buf = make([]byte, len(rest), len(rest)+chunkSize)
+		copy(buf, rest)
+		searched = len(buf)
+	}
+	close(chunks)
+	wg.Wait()
+
+	// reduce the per-worker maps into the first one
+	counts := partial[0]
+	for _, other := range partial[1:] {
+		MergeCounts(counts, other)
+	}
+	if readErr != nil {
+		return counts, readErr
+	}
+	for _, err := range errs {
+		if err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+// lastSpace returns the index of the last ASCII whitespace byte in b, or -1.
+// bufio.ScanWords treats all of these as separators, and they never appear
+// inside a multi-byte UTF-8 sequence, so cutting after one is always safe.
+func lastSpace(b []byte) int {
+	for i := len(b) - 1; i >= 0; i-- {
+		switch b[i] {
+		case ' ', '\t', '\n', '\v', '\f', '\r':
+			return i
+		}
+	}
+	return -1
+}
+
+// MergeCounts adds the counts of src to dst. It does not apply
+// Options.MaxDistinct, as the words of src come in no particular order; use
+// CountWordsInto to count several inputs under one limit.
+func MergeCounts(dst, src map[string]int) {
+	for word, count := range src {
+		dst[word] += count
+	}
+}
+
+// Count is a word and how many times it occurs.
+type Count struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+	// Order numbers the words by their first appearance, for callers that
+	// break ties in that order
+	Order int `json:"-"`
+}
+
+// porterStem returns the stem of the lowercase word under the Porter stemming
+// algorithm, as described in M.F. Porter, "An algorithm for suffix stripping",
+// 1980. Words of two letters or less and words with anything other than the
+// letters a to z are returned unchanged.
+func porterStem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	z := &stemmer{b: []byte(word), k: len(word) - 1}
+	z.step1ab()
+	if z.k > 0 {
+		z.step1c()
+		z.step2()
+		z.step3()
+		z.step4()
+		z.step5()
+	}
+	return string(z.b[:z.k+1])
+}
+
+// stemmer holds a word being stemmed: b[:k+1] is the current word and j marks
+// the end of the stem once ends has matched a suffix.
+type stemmer struct {
+	b    []byte
+	k, j int
+}
+
+// cons reports whether b[i] is a consonant.
+func (z *stemmer) cons(i int) bool {
+	switch z.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !z.cons(i-1)
+	}
+	return true
+}
+
+// m measures the number of consonant sequences in b[:j+1]. With c for a run
+// of consonants and v for a run of vowels, [c](vc){m}[v] has measure m.
+func (z *stemmer) m() int {
+	n, i := 0, 0
+	for i <= z.j && z.cons(i) {
+		i++
+	}
+	for {
+		for i <= z.j && !z.cons(i) {
+			i++
+		}
+		if i > z.j {
+			return n
+		}
+		for i <= z.j && z.cons(i) {
+			i++
+		}
+		n++
+	}
+}
+
+// vowelInStem reports whether b[:j+1] contains a vowel.
+func (z *stemmer) vowelInStem() bool {
+	for i := 0; i <= z.j; i++ {
+		if !z.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleC reports whether b[i-1:i+1] is a double consonant.
+func (z *stemmer) doubleC(i int) bool {
+	return i >= 1 && z.b[i] == z.b[i-1] && z.cons(i)
+}
+
+// cvc reports whether b[i-2:i+1] is consonant, vowel, consonant and the last
+// consonant is not w, x or y. It marks short words like cav(e) and hop(e).
+func (z *stemmer) cvc(i int) bool {
+	if i < 2 || !z.cons(i) || z.cons(i-1) || !z.cons(i-2) {
+		return false
+	}
+	switch z.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends reports whether the word ends with suffix, and if so sets j to the end
+// of the stem before it.
+func (z *stemmer) ends(suffix string) bool {
+	n := len(suffix)
+	if n > z.k+1 || string(z.b[z.k+1-n:z.k+1]) != suffix {
+		return false
+	}
+	z.j = z.k - n
+	return true
+}
+
+// setTo replaces the word after the stem, b[j+1:k+1], with s.
+func (z *stemmer) setTo(s string) {
+	z.b = append(z.b[:z.j+1], s...)
+	z.k = z.j + len(s)
+}
+
+// r replaces the suffix with s when the stem has a measure above 0.
+func (z *stemmer) r(s string) {
+	if z.m() > 0 {
+		z.setTo(s)
+	}
+}
+
+// step1ab removes plurals and -ed or -ing, as in caresses, ponies, cats,
+// agreed, plastered and motoring.
+func (z *stemmer) step1ab() {
+	if z.b[z.k] == 's' {
+		switch {
+		case z.ends("sses"):
+			z.k -= 2
+		case z.ends("ies"):
+			z.setTo("i")
+		case z.b[z.k-1] != 's':
+			z.k--
+		}
+	}
+	if z.ends("eed") {
+		if z.m() > 0 {
+			z.k--
+		}
+		return
+	}
+	if (z.ends("ed") || z.ends("ing")) && z.vowelInStem() {
+		z.k = z.j
+		switch {
+		case z.ends("at"):
+			z.setTo("ate")
+		case z.ends("bl"):
+			z.setTo("ble")
+		case z.ends("iz"):
+			z.setTo("ize")
+		case z.doubleC(z.k):
+			switch z.b[z.k] {
+			case 'l', 's', 'z':
+			default:
+				z.k--
+			}
+		case z.m() == 1 && z.cvc(z.k):
+			z.setTo("e")
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there is another vowel in the stem.
+func (z *stemmer) step1c() {
+	if z.ends("y") && z.vowelInStem() {
+		z.b[z.k] = 'i'
+	}
+}
+
+// step2 maps double suffixes to single ones, so -ization becomes -ize.
+func (z *stemmer) step2() {
+	for _, rule := range step2Rules[z.b[z.k-1]] {
+		if z.ends(rule[0]) {
+			z.r(rule[1])
+			return
+		}
+	}
+}
+
+var step2Rules = map[byte][][2]string{
+	'a': {{"ational", "ate"}, {"tional", "tion"}},
+	'c': {{"enci", "ence"}, {"anci", "ance"}},
+	'e': {{"izer", "ize"}},
+	'l': {{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}},
+	'o': {{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}},
+	's': {{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}},
+	't': {{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}},
+	'g': {{"logi", "log"}},
+}
+
+// step3 deals with -ic-, -full, -ness and the like.
+func (z *stemmer) step3() {
+	for _, rule := range step3Rules[z.b[z.k]] {
+		if z.ends(rule[0]) {
+			z.r(rule[1])
+			return
+		}
+	}
+}
+
+var step3Rules = map[byte][][2]string{
+	'e': {{"icate", "ic"}, {"ative", ""}, {"alize", "al"}},
+	'i': {{"iciti", "ic"}},
+	'l': {{"ical", "ic"}, {"ful", ""}},
+	's': {{"ness", ""}},
+}
+
+// step4 takes off -ant, -ence and the like when the stem has a measure
+// above 1.
+func (z *stemmer) step4() {
+	matched := false
+	for _, suffix := range step4Suffixes[z.b[z.k-1]] {
+		if z.ends(suffix) {
+			matched = true
+			break
+		}
+	}
+	// -ion only goes after s or t
+	if !matched && z.b[z.k-1] == 'o' {
+		if z.ends("ion") && z.j >= 0 && (z.b[z.j] == 's' || z.b[z.j] == 't') {
+			matched = true
+		} else if z.ends("ou") {
+			matched = true
+		}
+	}
+	if matched && z.m() > 1 {
+		z.k = z.j
+	}
+}
+
+var step4Suffixes = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+// step5 removes a final -e and turns -ll into -l when the stem has a measure
+// above 1.
+func (z *stemmer) step5() {
+	z.j = z.k
+	if z.b[z.k] == 'e' {
+		a := z.m()
+		if a > 1 || a == 1 && !z.cvc(z.k-1) {
+			z.k--
+		}
+	}
+	if z.b[z.k] == 'l' && z.doubleC(z.k) && z.m() > 1 {
+		z.k--
+	}
+}
@@ -0,0 +1,647 @@
+package wordcount
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestCountWords(t *testing.T) {
+	got, err := CountWords(strings.NewReader("The cat and the hat.\nthe END end\n"), Options{Lowercase: true, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"the": 3, "cat": 1, "and": 1, "hat.": 1, "end": 2}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsEmpty(t *testing.T) {
+	got, err := CountWords(strings.NewReader(" \n\t\n"), Options{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("CountWords of blank input = %v, want no words", got)
+	}
+}
+
+// benchInput is a synthetic text of about 1MB with a few dozen distinct
+// words in mixed case, the shape of input.txt.
+var benchInput = func() string {
+	var sb strings.Builder
+	words := strings.Fields("the quick brown fox jumps over the lazy dog while THE Dog sleeps")
+	for i := 0; sb.Len() < 1<<20; i++ {
+		sb.WriteString(words[i%len(words)])
+		if i%7 == 0 {
+			// suffixed words give the map more than a handful of keys
+			sb.WriteString(strings.Repeat("x", i%5))
+		}
+		if i%12 == 11 {
+			sb.WriteByte('\n')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	return sb.String()
+}()
+
+func BenchmarkCountWords(b *testing.B) {
+	opts := Options{Lowercase: true, Workers: 1}
+	b.SetBytes(int64(len(benchInput)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := CountWords(strings.NewReader(benchInput), opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCountWordsTokenRegex(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		regex string
+		want  map[string]int
+	}{
+		{"letters", "it's a dog-eat-dog world\r\n\n--- 42 ---\nworld", `\p{L}+`,
+			map[string]int{"it": 1, "s": 1, "a": 1, "dog": 2, "eat": 1, "world": 2}},
+		// the line is matched as a whole, not from where the last word ended
+		{"anchored", "one two\nthree four\n", `^\w+`, map[string]int{"one": 1, "three": 1}},
+		// more matches on the last line than the scanner allows tokens without progress
+		{"long last line", strings.Repeat("w ", 199) + "w", `\w+`, map[string]int{"w": 200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CountWords(strings.NewReader(tt.input), Options{TokenRegex: regexp.MustCompile(tt.regex), Workers: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("CountWords = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// stopAtEOF reads r and closes done when r runs out, like a ctrl-C during a
+// read that then ends the input.
+type stopAtEOF struct {
+	r    io.Reader
+	done chan struct{}
+}
+
+func (s *stopAtEOF) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err == io.EOF && !stopped(s.done) {
+		close(s.done)
+	}
+	return n, err
+}
+
+func TestCountWordsStoppedAtEOF(t *testing.T) {
+	for _, workers := range []int{1, 2} {
+		done := make(chan struct{})
+		r := &stopAtEOF{r: strings.NewReader("a b c\n"), done: done}
+		counts, err := CountWords(r, Options{Workers: workers, Done: done})
+		if !errors.Is(err, ErrStopped) {
+			t.Errorf("Workers %d: CountWords error = %v, want ErrStopped", workers, err)
+		}
+		if counts["a"] != 1 {
+			t.Errorf("Workers %d: CountWords = %v, want the words read before the stop", workers, counts)
+		}
+	}
+}
+
+func TestCountWordsIntoMaxDistinct(t *testing.T) {
+	counts := map[string]int{"a": 1}
+	limited := false
+	opts := Options{MaxDistinct: 3, Workers: 1, LimitReached: func() { limited = true }}
+	if err := CountWordsInto(counts, strings.NewReader("b c a d b e"), opts); err != nil {
+		t.Fatal(err)
+	}
+	// the words already in counts take up room, and words past the limit are dropped
+	want := map[string]int{"a": 2, "b": 2, "c": 1}
+	if !maps.Equal(counts, want) {
+		t.Errorf("CountWordsInto = %v, want %v", counts, want)
+	}
+	if !limited {
+		t.Error("LimitReached was not called")
+	}
+}
+
+func TestCountWordsFirstPos(t *testing.T) {
+	got, err := CountWords(strings.NewReader("The cat saw the dog\nCAT"), Options{Lowercase: true, FirstPos: true, Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"the": 1, "cat": 2, "saw": 3, "dog": 5}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with FirstPos = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsApproxTop(t *testing.T) {
+	// a skewed input: word i occurs 1000/i times, with a long tail of words seen once
+	var sb strings.Builder
+	for i := 1; i <= 5; i++ {
+		for j := 0; j < 1000/i; j++ {
+			fmt.Fprintf(&sb, "w%d ", i)
+		}
+	}
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&sb, "rare%d ", i)
+	}
+	exact, err := CountWords(strings.NewReader(sb.String()), Options{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	approx, err := CountWords(strings.NewReader(sb.String()), Options{ApproxTop: 5, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approx) != 5 {
+		t.Fatalf("ApproxTop 5 kept %d words: %v", len(approx), approx)
+	}
+	for i := 1; i <= 5; i++ {
+		word := fmt.Sprintf("w%d", i)
+		// the sketch may count too high but never too low
+		if got, want := approx[word], exact[word]; got < want || got > want+want/10 {
+			t.Errorf("ApproxTop count of %s = %d, want %d or a little more", word, got, want)
+		}
+	}
+}
+
+func TestCountWordsSkipInvalid(t *testing.T) {
+	// \xff and the cut off \xe2\x82 are not UTF-8; \xef\xbf\xbd is a real U+FFFD
+	input := "ok \xff caf\xc3\xa9 \xe2\x82 \xef\xbf\xbd ok\n"
+	tests := []struct {
+		name    string
+		opts    Options
+		want    map[string]int
+		invalid int
+	}{
+		{"words", Options{SkipInvalid: true}, map[string]int{"ok": 2, "café": 1, "�": 1}, 2},
+		{"chars", Options{Chars: true, SkipInvalid: true},
+			map[string]int{"o": 2, "k": 2, "c": 1, "a": 1, "f": 1, "é": 1, "�": 1}, 3},
+		{"kept", Options{}, map[string]int{"ok": 2, "\xff": 1, "café": 1, "\xe2\x82": 1, "�": 1}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := 0
+			opts := tt.opts
+			opts.Workers = 1
+			opts.Invalid = func() { invalid++ }
+			got, err := CountWords(strings.NewReader(input), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("CountWords = %q, want %q", got, tt.want)
+			}
+			if invalid != tt.invalid {
+				t.Errorf("Invalid called %d times, want %d", invalid, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestCountWordsStopWords(t *testing.T) {
+	opts := Options{Lowercase: true, StopWords: map[string]bool{"the": true, "a": true}, Workers: 1}
+	got, err := CountWords(strings.NewReader("The cat saw a dog, THE end"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stop words are matched after lowercasing
+	want := map[string]int{"cat": 1, "saw": 1, "dog,": 1, "end": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsParallel(t *testing.T) {
+	// benchInput spans many chunks, so words are cut between them
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"words", Options{Lowercase: true}},
+		{"strip punct", Options{Lowercase: true, StripPunct: true, MinLen: 3}},
+		{"chars", Options{Chars: true}},
+		{"delimiter", Options{Delimiter: ' '}},
+		{"token regex", Options{TokenRegex: regexp.MustCompile(`[a-z]+`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serial, parallel := tt.opts, tt.opts
+			serial.Workers, parallel.Workers = 1, 4
+			want, err := CountWords(strings.NewReader(benchInput), serial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := CountWords(strings.NewReader(benchInput), parallel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, want) {
+				t.Errorf("counts with 4 workers differ from the serial counts")
+			}
+		})
+	}
+}
+
+func TestCountWordsNGram(t *testing.T) {
+	tests := []struct {
+		input string
+		n     int
+		want  map[string]int
+	}{
+		{"a b c\na b", 2, map[string]int{"a b": 2, "b c": 1, "c a": 1}},
+		{"a b c a b", 3, map[string]int{"a b c": 1, "b c a": 1, "c a b": 1}},
+		// fewer words than N make no phrase
+		{"a b", 3, map[string]int{}},
+	}
+	for _, tt := range tests {
+		got, err := CountWords(strings.NewReader(tt.input), Options{NGram: tt.n, Workers: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("CountWords(%q) with NGram %d = %v, want %v", tt.input, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCountWordsMaxTokenSize(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	input := "a " + long + " a\n"
+	if _, err := CountWords(strings.NewReader(input), Options{Workers: 1}); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("CountWords of a 100KB word with the default buffer: error = %v, want bufio.ErrTooLong", err)
+	}
+	for _, workers := range []int{1, 4} {
+		got, err := CountWords(strings.NewReader(input), Options{MaxTokenSize: 1 << 20, Workers: workers})
+		if err != nil {
+			t.Fatalf("Workers %d: %v", workers, err)
+		}
+		if got[long] != 1 || got["a"] != 2 {
+			t.Errorf("Workers %d: the 100KB word was not counted", workers)
+		}
+	}
+}
+
+func TestCountWordsTurkish(t *testing.T) {
+	opts := Options{Lowercase: true, Lang: language.Turkish, Workers: 1}
+	got, err := CountWords(strings.NewReader("İstanbul ISPARTA istanbul"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// dotted İ lowers to i and dotless I to ı
+	want := map[string]int{"istanbul": 2, "ısparta": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with Lang tr = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsLength(t *testing.T) {
+	// lengths are in characters, not bytes
+	got, err := CountWords(strings.NewReader("a héllo wörld abcdefg 日本 go"), Options{MinLen: 2, MaxLen: 5, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"héllo": 1, "wörld": 1, "日本": 1, "go": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with MinLen 2 and MaxLen 5 = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsHyphensAndContractions(t *testing.T) {
+	input := `"Don't" stop the state-of-the-art art, don't!`
+	tests := []struct {
+		name string
+		opts Options
+		want map[string]int
+	}{
+		{"strip punct", Options{Lowercase: true, StripPunct: true},
+			map[string]int{"don't": 2, "stop": 1, "the": 1, "state-of-the-art": 1, "art": 1}},
+		{"split hyphens", Options{Lowercase: true, StripPunct: true, SplitHyphens: true},
+			map[string]int{"don't": 2, "stop": 1, "the": 2, "state": 1, "of": 1, "art": 2}},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		opts.Workers = 1
+		got, err := CountWords(strings.NewReader(input), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("%s: CountWords = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCountWordsInclude(t *testing.T) {
+	// the pattern sees the lowercased words
+	opts := Options{Lowercase: true, Include: regexp.MustCompile(`^[a-z]+$`), Workers: 1}
+	got, err := CountWords(strings.NewReader("Cat cat2 DOG 42 cat"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"cat": 2, "dog": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with Include = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsNoNumbers(t *testing.T) {
+	got, err := CountWords(strings.NewReader("42 3rd ٤٢ 7 v2 1,000"), Options{NoNumbers: true, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only words made of nothing but digits, in any script, are numbers
+	want := map[string]int{"3rd": 1, "v2": 1, "1,000": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with NoNumbers = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsChars(t *testing.T) {
+	got, err := CountWords(strings.NewReader("Héé 日\tA\n"), Options{Chars: true, Lowercase: true, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// multi-byte characters count once each, and whitespace not at all
+	want := map[string]int{"h": 1, "é": 2, "日": 1, "a": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with Chars = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsFold(t *testing.T) {
+	got, err := CountWords(strings.NewReader("STRASSE straße Straße strasse"), Options{Lowercase: true, Fold: true, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("CountWords with Fold = %v, want the spellings as one word", got)
+	}
+	for word, count := range got {
+		if count != 4 {
+			t.Errorf("CountWords with Fold counted %q %d times, want 4", word, count)
+		}
+	}
+	// Greek letters with a full folding of several characters
+	got, err = CountWords(strings.NewReader("ᾳ ΑΙ αι \u0390 \u1fd3"), Options{Lowercase: true, Fold: true, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"αι": 3, "\u03b9\u0308\u0301": 2}; !maps.Equal(got, want) {
+		t.Errorf("CountWords with Fold = %q, want %q", got, want)
+	}
+	// lowercasing alone keeps ß apart from ss
+	got, err = CountWords(strings.NewReader("STRASSE straße"), Options{Lowercase: true, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"strasse": 1, "straße": 1}; !maps.Equal(got, want) {
+		t.Errorf("CountWords with Lowercase = %v, want %v", got, want)
+	}
+}
+
+func TestPorterStem(t *testing.T) {
+	// examples from Porter's paper
+	tests := map[string]string{
+		"caresses": "caress", "ponies": "poni", "cats": "cat",
+		"run": "run", "runs": "run", "running": "run", "hopping": "hop",
+		"agreed": "agre", "happy": "happi", "relational": "relat",
+		"conditional": "condit", "generalizations": "gener", "sky": "sky",
+	}
+	for word, want := range tests {
+		if got := porterStem(word); got != want {
+			t.Errorf("porterStem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestCountWordsStem(t *testing.T) {
+	got, err := CountWords(strings.NewReader("Run runs running ran"), Options{Lowercase: true, Stem: true, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"run": 3, "ran": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with Stem = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsDelimiter(t *testing.T) {
+	input := "a, b ,c,,a\r\n b\n"
+	tests := []struct {
+		trim bool
+		want map[string]int
+	}{
+		{false, map[string]int{"a": 2, " b ": 1, "c": 1, " b": 1}},
+		{true, map[string]int{"a": 2, "b": 2, "c": 1}},
+	}
+	for _, tt := range tests {
+		got, err := CountWords(strings.NewReader(input), Options{Delimiter: ',', TrimFields: tt.trim, Workers: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// empty fields are skipped, and line ends end a field too
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("CountWords with TrimFields %v = %q, want %q", tt.trim, got, tt.want)
+		}
+	}
+}
+
+func TestCountWordsCooccur(t *testing.T) {
+	tests := []struct {
+		input  string
+		window int
+		want   map[string]int
+	}{
+		{"a b c", 2, map[string]int{"a b": 1, "b c": 1}},
+		{"a b c", 3, map[string]int{"a b": 1, "a c": 1, "b c": 1}},
+		// pairs are unordered, and a word is not paired with itself
+		{"b a b a a", 2, map[string]int{"a b": 3}},
+	}
+	for _, tt := range tests {
+		got, err := CountWords(strings.NewReader(tt.input), Options{Cooccur: tt.window, Workers: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("CountWords(%q) with Cooccur %d = %v, want %v", tt.input, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestCountWordsDupes(t *testing.T) {
+	got, err := CountWords(strings.NewReader("the The cat cat cat the dog\ndog"), Options{Lowercase: true, Dupes: true, Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each word equal to the one before is a repeat, across lines too
+	want := map[string]int{"the": 1, "cat": 2, "dog": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with Dupes = %v, want %v", got, want)
+	}
+}
+
+func TestCountWordsSample(t *testing.T) {
+	exact, err := CountWords(strings.NewReader(benchInput), Options{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Sample: 0.5, Seed: 7, Workers: 4}
+	first, err := CountWords(strings.NewReader(benchInput), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := CountWords(strings.NewReader(benchInput), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(first, again) {
+		t.Error("two samples with the same seed differ")
+	}
+	ScaleCounts(first, opts.Sample)
+	// "the" is the most frequent word, so its estimate is the tightest
+	if got, want := first["the"], exact["the"]; math.Abs(float64(got-want)) > 0.05*float64(want) {
+		t.Errorf("estimate of %q = %d, want within 5%% of %d", "the", got, want)
+	}
+	// a probability of 1 takes every word
+	all, err := CountWords(strings.NewReader(benchInput), Options{Sample: 1, Seed: 7, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(all, exact) {
+		t.Error("Sample 1 counts differ from the exact counts")
+	}
+}
+
+func TestCountWordsLowercase(t *testing.T) {
+	tests := []struct {
+		lowercase bool
+		want      map[string]int
+	}{
+		{true, map[string]int{"go": 3}},
+		{false, map[string]int{"Go": 1, "go": 1, "GO": 1}},
+	}
+	for _, tt := range tests {
+		got, err := CountWords(strings.NewReader("Go go GO"), Options{Lowercase: tt.lowercase, Workers: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("CountWords with Lowercase %v = %v, want %v", tt.lowercase, got, tt.want)
+		}
+	}
+}
+
+// listTokenizer hands out the tokens of a list, then err.
+type listTokenizer struct {
+	tokens []string
+	err    error
+}
+
+func (l *listTokenizer) Next() (string, bool) {
+	if len(l.tokens) == 0 {
+		return "", false
+	}
+	token := l.tokens[0]
+	l.tokens = l.tokens[1:]
+	return token, true
+}
+
+func (l *listTokenizer) Err() error { return l.err }
+
+func TestCountWordsTokenizer(t *testing.T) {
+	errRead := errors.New("read failed")
+	opts := Options{
+		Lowercase: true,
+		MinLen:    2,
+		Workers:   4,
+		Tokenizer: func(r io.Reader) Tokenizer {
+			data, _ := io.ReadAll(r)
+			return &listTokenizer{tokens: strings.Split(string(data), ","), err: errRead}
+		},
+	}
+	got, err := CountWords(strings.NewReader("New York,x,new york,Paris"), opts)
+	if !errors.Is(err, errRead) {
+		t.Errorf("CountWords error = %v, want the Tokenizer's error", err)
+	}
+	// the tokens are filtered like the words of the default tokenizer
+	want := map[string]int{"new york": 2, "paris": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountWords with a Tokenizer = %v, want %v", got, want)
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestCountWordsParallelTooLong(t *testing.T) {
+	// 8MB without a space or a line end
+	input := strings.Repeat("x", 8<<20)
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"words", Options{}},
+		{"lines", Options{Delimiter: ','}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &countingReader{r: strings.NewReader(input)}
+			opts := tt.opts
+			opts.Workers = 4
+			if _, err := CountWords(r, opts); !errors.Is(err, bufio.ErrTooLong) {
+				t.Errorf("CountWords error = %v, want bufio.ErrTooLong", err)
+			}
+			// reading stops soon after the limit is passed
+			if r.n > bufio.MaxScanTokenSize+2*chunkSize {
+				t.Errorf("read %d bytes before giving up, want at most %d", r.n, bufio.MaxScanTokenSize+2*chunkSize)
+			}
+			// a larger buffer takes the whole input as one word
+			opts.MaxTokenSize = 16 << 20
+			got, err := CountWords(strings.NewReader(input), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[input] != 1 {
+				t.Errorf("the 8MB word was not counted with a 16MB buffer")
+			}
+		})
+	}
+}
+
+func TestCountWordsIntoApproxTop(t *testing.T) {
+	counts := map[string]int{"a": 100}
+	if err := CountWordsInto(counts, strings.NewReader("a a b"), Options{ApproxTop: 5, Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// the estimates are added to the counts already there
+	want := map[string]int{"a": 102, "b": 1}
+	if !maps.Equal(counts, want) {
+		t.Errorf("CountWordsInto with ApproxTop = %v, want %v", counts, want)
+	}
+}